@@ -0,0 +1,449 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/expectedsh/go-sonic/sonic"
+)
+
+// benchOps are the operations of a bench workload.
+var benchOps = []string{"push", "pop", "query", "suggest", "list", "count"}
+
+// benchConfig is a synthetic workload.
+type benchConfig struct {
+	duration    time.Duration
+	concurrency int
+	rate        int
+	mix         map[string]int
+
+	collection string
+	buckets    int
+	objects    int
+	prefill    int
+	lang       sonic.Lang
+
+	vocabulary    int
+	distribution  string
+	zipfS         float64
+	wordsPerText  int
+	wordsPerQuery int
+	seed          int64
+}
+
+func runBench(c *client, args []string, out io.Writer) error {
+	cfg := benchConfig{}
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	fs.DurationVar(&cfg.duration, "duration", 10*time.Second, "duration of the measure")
+	fs.IntVar(&cfg.concurrency, "concurrency", 8, "number of workers, each one with its own connections")
+	fs.IntVar(&cfg.rate, "rate", 0, "maximum operations per second of all the workers, up to 1e9, 0 for no limit")
+	mix := fs.String("mix", "query=60,suggest=20,push=20", "weights of the operations among "+strings.Join(benchOps, ", "))
+	fs.StringVar(&cfg.collection, "collection", "bench", "collection used by the workload")
+	fs.IntVar(&cfg.buckets, "buckets", 1, "number of buckets")
+	fs.IntVar(&cfg.objects, "objects", 10000, "number of distinct objects pushed and popped")
+	fs.IntVar(&cfg.prefill, "prefill", 1000, "number of objects pushed before the measure, so queries find results")
+	lang := fs.String("lang", "none", "language of the texts and queries, empty to detect it")
+	fs.IntVar(&cfg.vocabulary, "vocabulary", 5000, "number of distinct words")
+	fs.StringVar(&cfg.distribution, "distribution", "zipf", "distribution of the words, zipf or uniform")
+	fs.Float64Var(&cfg.zipfS, "zipf-s", 1.1, "exponent of the zipf distribution, greater than 1")
+	fs.IntVar(&cfg.wordsPerText, "text-words", 8, "number of words of a pushed text")
+	fs.IntVar(&cfg.wordsPerQuery, "query-words", 2, "number of words of a query")
+	fs.Int64Var(&cfg.seed, "seed", 1, "seed of the random workload")
+	cleanup := fs.Bool("cleanup", true, "flush the collection at the end")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if _, err := parseArgs(lookup("bench"), fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	var err error
+	cfg.lang = sonic.Lang(*lang)
+	if cfg.mix, err = parseMix(*mix); err != nil {
+		return &usageError{lookup("bench"), err.Error(), ""}
+	}
+	if cfg.concurrency <= 0 || cfg.rate < 0 || cfg.rate > int(time.Second) || cfg.buckets <= 0 || cfg.objects <= 0 || cfg.vocabulary <= 1 ||
+		cfg.wordsPerText <= 0 || cfg.wordsPerQuery <= 0 || (cfg.distribution == "zipf" && cfg.zipfS <= 1) ||
+		(cfg.distribution != "zipf" && cfg.distribution != "uniform") {
+		return &usageError{lookup("bench"), "invalid workload", ""}
+	}
+
+	ingest, err := c.ingest()
+	if err != nil {
+		return err
+	}
+	if cfg.prefill > 0 {
+		if err := prefill(ingest, cfg); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	report, err := bench(ctx, c, cfg)
+	if err != nil {
+		return err
+	}
+	if *cleanup {
+		if err := ingest.FlushCollection(cfg.collection); err != nil {
+			return err
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	printBenchReport(out, report)
+	return nil
+}
+
+// parseMix parse weights like query=60,push=40.
+func parseMix(s string) (map[string]int, error) {
+	mix := make(map[string]int)
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		w, err := strconv.Atoi(weight)
+		if !ok || err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid mix %q, expected op=weight,...", part)
+		}
+		if !isBenchOp(name) {
+			return nil, fmt.Errorf("unknown operation %q in mix", name)
+		}
+		mix[name] += w
+		total += w
+	}
+	if total == 0 {
+		return nil, errors.New("mix has no operation")
+	}
+	return mix, nil
+}
+
+func isBenchOp(name string) bool {
+	for _, op := range benchOps {
+		if op == name {
+			return true
+		}
+	}
+	return false
+}
+
+// benchWorkload generate the random operations of a worker.
+type benchWorkload struct {
+	cfg  benchConfig
+	rand *rand.Rand
+	zipf *rand.Zipf
+
+	// ops has an operation name per unit of weight
+	ops []string
+}
+
+func newBenchWorkload(cfg benchConfig, seed int64) *benchWorkload {
+	w := &benchWorkload{cfg: cfg, rand: rand.New(rand.NewSource(seed))}
+	if cfg.distribution == "zipf" {
+		w.zipf = rand.NewZipf(w.rand, cfg.zipfS, 1, uint64(cfg.vocabulary-1))
+	}
+	for _, op := range benchOps {
+		for n := 0; n < cfg.mix[op]; n++ {
+			w.ops = append(w.ops, op)
+		}
+	}
+	return w
+}
+
+func (w *benchWorkload) op() string {
+	return w.ops[w.rand.Intn(len(w.ops))]
+}
+
+func (w *benchWorkload) word() string {
+	if w.zipf != nil {
+		return benchWord(int(w.zipf.Uint64()))
+	}
+	return benchWord(w.rand.Intn(w.cfg.vocabulary))
+}
+
+func (w *benchWorkload) words(n int) string {
+	words := make([]string, n)
+	for i := range words {
+		words[i] = w.word()
+	}
+	return strings.Join(words, " ")
+}
+
+func (w *benchWorkload) bucket() string {
+	return "b" + strconv.Itoa(w.rand.Intn(w.cfg.buckets))
+}
+
+func (w *benchWorkload) object() string {
+	return "o" + strconv.Itoa(w.rand.Intn(w.cfg.objects))
+}
+
+var (
+	benchConsonants = "bdfgklmnprstvz"
+	benchVowels     = "aeiou"
+)
+
+// benchWord return a pronounceable word for the index n of the vocabulary,
+// so the words look like natural ones to sonic, eg. bakotu.
+func benchWord(n int) string {
+	// skip the single syllables, too short to be indexed
+	n += len(benchConsonants) * len(benchVowels)
+
+	var b strings.Builder
+	for n > 0 {
+		b.WriteByte(benchConsonants[n%len(benchConsonants)])
+		n /= len(benchConsonants)
+		b.WriteByte(benchVowels[n%len(benchVowels)])
+		n /= len(benchVowels)
+	}
+	return b.String()
+}
+
+// prefill push the first objects, so queries and suggestions find results.
+func prefill(ingest sonic.Ingestable, cfg benchConfig) error {
+	w := newBenchWorkload(cfg, cfg.seed-1)
+	records := make(map[string][]sonic.IngestBulkRecord)
+	for n := 0; n < cfg.prefill && n < cfg.objects; n++ {
+		bucket := "b" + strconv.Itoa(n%cfg.buckets)
+		records[bucket] = append(records[bucket], sonic.IngestBulkRecord{
+			Object: "o" + strconv.Itoa(n),
+			Text:   w.words(cfg.wordsPerText),
+		})
+	}
+	for bucket, recs := range records {
+		if errs := ingest.BulkPush(cfg.collection, bucket, cfg.concurrency, recs, cfg.lang); len(errs) > 0 {
+			return fmt.Errorf("prefill of %s failed: %v", errs[0].Object, errs[0].Error)
+		}
+	}
+	return nil
+}
+
+// benchSample is the result of an operation.
+type benchSample struct {
+	latency time.Duration
+	err     error
+}
+
+// bench run the workload until its duration is elapsed or ctx is done.
+func bench(ctx context.Context, c *client, cfg benchConfig) (*benchReport, error) {
+	var tokens <-chan time.Time
+	if cfg.rate > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(cfg.rate))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+
+	// connect every worker before starting the clock
+	type worker struct {
+		search sonic.Searchable
+		ingest sonic.Ingestable
+	}
+	workers := make([]worker, cfg.concurrency)
+	defer func() {
+		for _, w := range workers {
+			if w.search != nil {
+				_ = w.search.Quit()
+			}
+			if w.ingest != nil {
+				_ = w.ingest.Quit()
+			}
+		}
+	}()
+	for n := range workers {
+		var err error
+		if workers[n].search, err = sonic.NewSearch(c.host, c.port, c.password, c.opts...); err != nil {
+			return nil, err
+		}
+		if workers[n].ingest, err = sonic.NewIngester(c.host, c.port, c.password, c.opts...); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.duration)
+	defer cancel()
+
+	samples := make([]map[string][]benchSample, cfg.concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for n := range workers {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			load := newBenchWorkload(cfg, cfg.seed+int64(n))
+			samples[n] = make(map[string][]benchSample)
+			for {
+				if tokens != nil {
+					select {
+					case <-ctx.Done():
+						return
+					case <-tokens:
+					}
+				} else if ctx.Err() != nil {
+					return
+				}
+
+				op := load.op()
+				opStart := time.Now()
+				err := benchOp(workers[n].search, workers[n].ingest, load, op)
+				samples[n][op] = append(samples[n][op], benchSample{time.Since(opStart), err})
+			}
+		}(n)
+	}
+	wg.Wait()
+
+	merged := make(map[string][]benchSample)
+	for _, s := range samples {
+		for op, opSamples := range s {
+			merged[op] = append(merged[op], opSamples...)
+		}
+	}
+	return newBenchReport(cfg, time.Since(start), merged), nil
+}
+
+func benchOp(search sonic.Searchable, ingest sonic.Ingestable, w *benchWorkload, op string) (err error) {
+	c := w.cfg.collection
+	switch op {
+	case "push":
+		err = ingest.Push(c, w.bucket(), w.object(), w.words(w.cfg.wordsPerText), w.cfg.lang)
+	case "pop":
+		err = ingest.Pop(c, w.bucket(), w.object(), w.word())
+	case "query":
+		_, err = search.Query(c, w.bucket(), w.words(w.cfg.wordsPerQuery), 10, 0, w.cfg.lang)
+	case "suggest":
+		_, err = search.Suggest(c, w.bucket(), w.word()[:3], 5)
+	case "list":
+		_, err = search.List(c, w.bucket(), 10, 0)
+	case "count":
+		_, err = ingest.Count(c, w.bucket(), "")
+	}
+	return err
+}
+
+// benchReport is the result of a bench, printed as text or JSON.
+type benchReport struct {
+	Duration    float64         `json:"duration_seconds"`
+	Concurrency int             `json:"concurrency"`
+	Total       benchStats      `json:"total"`
+	Commands    []benchStats    `json:"commands"`
+	Config      benchReportConf `json:"config"`
+}
+
+type benchReportConf struct {
+	Rate         int            `json:"rate"`
+	Mix          map[string]int `json:"mix"`
+	Distribution string         `json:"distribution"`
+	Vocabulary   int            `json:"vocabulary"`
+	Objects      int            `json:"objects"`
+	Buckets      int            `json:"buckets"`
+}
+
+// benchStats are the statistics of a command, latencies are in milliseconds.
+type benchStats struct {
+	Command    string  `json:"command"`
+	Ops        int     `json:"ops"`
+	Errors     int     `json:"errors"`
+	LastError  string  `json:"last_error,omitempty"`
+	Throughput float64 `json:"ops_per_second"`
+	Mean       float64 `json:"mean_ms"`
+	P50        float64 `json:"p50_ms"`
+	P90        float64 `json:"p90_ms"`
+	P99        float64 `json:"p99_ms"`
+	Max        float64 `json:"max_ms"`
+}
+
+func newBenchReport(cfg benchConfig, elapsed time.Duration, samples map[string][]benchSample) *benchReport {
+	r := &benchReport{
+		Duration:    elapsed.Seconds(),
+		Concurrency: cfg.concurrency,
+		Config: benchReportConf{
+			Rate:         cfg.rate,
+			Mix:          cfg.mix,
+			Distribution: cfg.distribution,
+			Vocabulary:   cfg.vocabulary,
+			Objects:      cfg.objects,
+			Buckets:      cfg.buckets,
+		},
+	}
+	var all []benchSample
+	for _, op := range benchOps {
+		if len(samples[op]) == 0 {
+			continue
+		}
+		r.Commands = append(r.Commands, newBenchStats(op, samples[op], elapsed))
+		all = append(all, samples[op]...)
+	}
+	r.Total = newBenchStats("total", all, elapsed)
+	return r
+}
+
+func newBenchStats(command string, samples []benchSample, elapsed time.Duration) benchStats {
+	s := benchStats{Command: command, Ops: len(samples)}
+	if len(samples) == 0 {
+		return s
+	}
+
+	latencies := make([]time.Duration, len(samples))
+	var sum time.Duration
+	for n, sample := range samples {
+		latencies[n] = sample.latency
+		sum += sample.latency
+		if sample.err != nil {
+			s.Errors++
+			s.LastError = sample.err.Error()
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	s.Throughput = float64(len(samples)) / elapsed.Seconds()
+	s.Mean = ms(sum / time.Duration(len(samples)))
+	s.P50 = ms(percentile(latencies, 50))
+	s.P90 = ms(percentile(latencies, 90))
+	s.P99 = ms(percentile(latencies, 99))
+	s.Max = ms(latencies[len(latencies)-1])
+	return s
+}
+
+// percentile return the p-th percentile of sorted latencies, with the nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func printBenchReport(out io.Writer, r *benchReport) {
+	fmt.Fprintf(out, "%.1fs, %d workers, %d ops (%.1f ops/s), %d errors\n\n",
+		r.Duration, r.Concurrency, r.Total.Ops, r.Total.Throughput, r.Total.Errors)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "command\tops\terrors\tops/s\tmean\tp50\tp90\tp99\tmax\t")
+	for _, s := range append(r.Commands, r.Total) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t\n",
+			s.Command, s.Ops, s.Errors, s.Throughput, s.Mean, s.P50, s.P90, s.P99, s.Max)
+	}
+	_ = tw.Flush()
+
+	for _, s := range r.Commands {
+		if s.LastError != "" {
+			fmt.Fprintf(out, "\nlast %s error: %s", s.Command, s.LastError)
+		}
+	}
+	if r.Total.Errors > 0 {
+		fmt.Fprintln(out)
+	}
+}
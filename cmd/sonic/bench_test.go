@@ -0,0 +1,60 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := parseMix("query=60, push=30,query=10")
+	if err != nil || !reflect.DeepEqual(mix, map[string]int{"query": 70, "push": 30}) {
+		t.Errorf("unexpected mix %v %v", mix, err)
+	}
+	for _, s := range []string{"query", "query=x", "foo=1", "query=0", "push=-1"} {
+		if _, err := parseMix(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+func TestBenchWord(t *testing.T) {
+	seen := make(map[string]int)
+	for n := 0; n < 10000; n++ {
+		w := benchWord(n)
+		if len(w) < 4 {
+			t.Fatalf("%d: word %q is too short", n, w)
+		}
+		if m, ok := seen[w]; ok {
+			t.Fatalf("%d and %d have the same word %q", m, n, w)
+		}
+		seen[w] = n
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for n := range latencies {
+		latencies[n] = time.Duration(n+1) * time.Millisecond
+	}
+	for p, expected := range map[int]time.Duration{50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(latencies, p); got != expected {
+			t.Errorf("p%d: expected %s, got %s", p, expected, got)
+		}
+	}
+	if got := percentile(latencies[:1], 50); got != time.Millisecond {
+		t.Errorf("expected the single latency, got %s", got)
+	}
+}
+
+func TestRunBench_Validation(t *testing.T) {
+	// a rate above one operation per nanosecond can't be paced by a ticker
+	for _, args := range [][]string{{"-rate", "2000000000"}, {"-rate", "-1"}, {"-concurrency", "0"}} {
+		var usage *usageError
+		if err := runBench(nil, args, io.Discard); !errors.As(err, &usage) {
+			t.Errorf("%v: expected a usage error, got %v", args, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import "github.com/expectedsh/go-sonic/sonic"
+
+// client open the channels on first use, so a command only connects the channel it needs.
+type client struct {
+	host     string
+	port     int
+	password string
+	opts     []sonic.Option
+
+	searchChannel  sonic.Searchable
+	ingestChannel  sonic.Ingestable
+	controlChannel sonic.Controllable
+}
+
+func newClient(host string, port int, password string, opts []sonic.Option) *client {
+	return &client{host: host, port: port, password: password, opts: opts}
+}
+
+func (c *client) search() (sonic.Searchable, error) {
+	if c.searchChannel == nil {
+		s, err := sonic.NewSearch(c.host, c.port, c.password, c.opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.searchChannel = s
+	}
+	return c.searchChannel, nil
+}
+
+func (c *client) ingest() (sonic.Ingestable, error) {
+	if c.ingestChannel == nil {
+		i, err := sonic.NewIngester(c.host, c.port, c.password, c.opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.ingestChannel = i
+	}
+	return c.ingestChannel, nil
+}
+
+func (c *client) control() (sonic.Controllable, error) {
+	if c.controlChannel == nil {
+		ctrl, err := sonic.NewControl(c.host, c.port, c.password, c.opts...)
+		if err != nil {
+			return nil, err
+		}
+		c.controlChannel = ctrl
+	}
+	return c.controlChannel, nil
+}
+
+// close quit the open channels.
+func (c *client) close() {
+	if c.searchChannel != nil {
+		_ = c.searchChannel.Quit()
+	}
+	if c.ingestChannel != nil {
+		_ = c.ingestChannel.Quit()
+	}
+	if c.controlChannel != nil {
+		_ = c.controlChannel.Quit()
+	}
+}
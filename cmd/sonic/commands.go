@@ -0,0 +1,322 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/expectedsh/go-sonic/sonic"
+)
+
+// command is a subcommand of the tool, also available in the shell.
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(c *client, args []string, out io.Writer) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"query", "[-limit n] [-offset n] [-lang code] <collection> <bucket> <terms...>", "search objects by terms", runQuery},
+		{"suggest", "[-limit n] <collection> <bucket> <word>", "auto-complete a word", runSuggest},
+		{"list", "[-limit n] [-offset n] <collection> <bucket>", "list the words of a bucket", runList},
+		{"push", "[-lang code] <collection> <bucket> <object> <text...>", "index the text of an object", runPush},
+		{"pop", "<collection> <bucket> <object> <text...>", "remove text from an object", runPop},
+		{"count", "<collection> [<bucket> [<object>]]", "count the buckets, objects or words", runCount},
+		{"flush", "<collection> [<bucket> [<object>]]", "flush a collection, bucket or object", runFlush},
+		{"import", "[flags] <collection> <file|->", "import records from a JSON Lines or CSV file", runImport},
+		{"bench", "[flags]", "load test the server with a synthetic workload", runBench},
+		{"trigger", "[<action>]", "trigger an action, consolidate by default", runTrigger},
+		{"info", "", "show the server statistics", runInfo},
+		{"ping", "", "check the server is up", runPing},
+		{"help", "[<command>]", "show the usage of the commands", runHelp},
+	}
+}
+
+func lookup(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// execute run the command line args.
+func execute(c *client, args []string, out io.Writer) error {
+	cmd := lookup(args[0])
+	if cmd == nil {
+		return &usageError{msg: fmt.Sprintf("unknown command %q, see help", args[0])}
+	}
+	return cmd.run(c, args[1:], out)
+}
+
+func printCommands(out io.Writer) {
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-8s %s\n", cmd.name, cmd.help)
+	}
+}
+
+// usageError is returned when a command is misused.
+type usageError struct {
+	cmd *command
+	msg string
+
+	// flags are the defaults of the command flags
+	flags string
+}
+
+func (e *usageError) Error() string {
+	if e.cmd == nil {
+		return e.msg
+	}
+	msg := fmt.Sprintf("%s\nusage: %s %s", e.msg, e.cmd.name, e.cmd.usage)
+	if e.flags != "" {
+		msg += "\n" + strings.TrimSuffix(e.flags, "\n")
+	}
+	return msg
+}
+
+// parseArgs parse the flags of a command and check the number of positional arguments,
+// maxArgs < 0 means no maximum.
+func parseArgs(cmd *command, fs *flag.FlagSet, args []string, minArgs, maxArgs int) ([]string, error) {
+	fs.SetOutput(io.Discard)
+	err := fs.Parse(args)
+	if err == nil && (fs.NArg() < minArgs || (maxArgs >= 0 && fs.NArg() > maxArgs)) {
+		err = errors.New("wrong number of arguments")
+	}
+	if err != nil {
+		var defaults strings.Builder
+		fs.SetOutput(&defaults)
+		fs.PrintDefaults()
+		return nil, &usageError{cmd, err.Error(), defaults.String()}
+	}
+	return fs.Args(), nil
+}
+
+func printLines(out io.Writer, lines []string) {
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+}
+
+func runQuery(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	limit := fs.Int("limit", 10, "maximum number of results")
+	offset := fs.Int("offset", 0, "number of results to skip")
+	lang := fs.String("lang", "", "language of the terms, eg. eng or none, detected by default")
+	pos, err := parseArgs(lookup("query"), fs, args, 3, -1)
+	if err != nil {
+		return err
+	}
+	search, err := c.search()
+	if err != nil {
+		return err
+	}
+	results, err := search.Query(pos[0], pos[1], strings.Join(pos[2:], " "), *limit, *offset, sonic.Lang(*lang))
+	if err != nil {
+		return err
+	}
+	printLines(out, results)
+	return nil
+}
+
+func runSuggest(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	limit := fs.Int("limit", 10, "maximum number of words")
+	pos, err := parseArgs(lookup("suggest"), fs, args, 3, 3)
+	if err != nil {
+		return err
+	}
+	search, err := c.search()
+	if err != nil {
+		return err
+	}
+	results, err := search.Suggest(pos[0], pos[1], pos[2], *limit)
+	if err != nil {
+		return err
+	}
+	printLines(out, results)
+	return nil
+}
+
+func runList(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", 100, "maximum number of words")
+	offset := fs.Int("offset", 0, "number of words to skip")
+	pos, err := parseArgs(lookup("list"), fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	search, err := c.search()
+	if err != nil {
+		return err
+	}
+	results, err := search.List(pos[0], pos[1], *limit, *offset)
+	if err != nil {
+		return err
+	}
+	printLines(out, results)
+	return nil
+}
+
+func runPush(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("push", flag.ContinueOnError)
+	lang := fs.String("lang", "", "language of the text, eg. eng or none, detected by default")
+	pos, err := parseArgs(lookup("push"), fs, args, 4, -1)
+	if err != nil {
+		return err
+	}
+	ingest, err := c.ingest()
+	if err != nil {
+		return err
+	}
+	err = ingest.Push(pos[0], pos[1], pos[2], strings.Join(pos[3:], " "), sonic.Lang(*lang))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "OK")
+	return nil
+}
+
+func runPop(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("pop", flag.ContinueOnError)
+	pos, err := parseArgs(lookup("pop"), fs, args, 4, -1)
+	if err != nil {
+		return err
+	}
+	ingest, err := c.ingest()
+	if err != nil {
+		return err
+	}
+	err = ingest.Pop(pos[0], pos[1], pos[2], strings.Join(pos[3:], " "))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "OK")
+	return nil
+}
+
+func runCount(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("count", flag.ContinueOnError)
+	pos, err := parseArgs(lookup("count"), fs, args, 1, 3)
+	if err != nil {
+		return err
+	}
+	pos = append(pos, "", "")
+	ingest, err := c.ingest()
+	if err != nil {
+		return err
+	}
+	n, err := ingest.Count(pos[0], pos[1], pos[2])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, n)
+	return nil
+}
+
+func runFlush(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("flush", flag.ContinueOnError)
+	pos, err := parseArgs(lookup("flush"), fs, args, 1, 3)
+	if err != nil {
+		return err
+	}
+	ingest, err := c.ingest()
+	if err != nil {
+		return err
+	}
+	switch len(pos) {
+	case 1:
+		err = ingest.FlushCollection(pos[0])
+	case 2:
+		err = ingest.FlushBucket(pos[0], pos[1])
+	default:
+		err = ingest.FlushObject(pos[0], pos[1], pos[2])
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "OK")
+	return nil
+}
+
+func runTrigger(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("trigger", flag.ContinueOnError)
+	pos, err := parseArgs(lookup("trigger"), fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	action := sonic.Consolidate
+	if len(pos) == 1 {
+		action = sonic.Action(pos[0])
+	}
+	control, err := c.control()
+	if err != nil {
+		return err
+	}
+	if err := control.Trigger(action); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "OK")
+	return nil
+}
+
+func runInfo(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("info", flag.ContinueOnError)
+	if _, err := parseArgs(lookup("info"), fs, args, 0, 0); err != nil {
+		return err
+	}
+	control, err := c.control()
+	if err != nil {
+		return err
+	}
+	info, err := control.Info()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(info))
+	for k := range info {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(out, "%s: %s\n", k, info[k])
+	}
+	return nil
+}
+
+func runPing(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("ping", flag.ContinueOnError)
+	if _, err := parseArgs(lookup("ping"), fs, args, 0, 0); err != nil {
+		return err
+	}
+	search, err := c.search()
+	if err != nil {
+		return err
+	}
+	if err := search.Ping(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "PONG")
+	return nil
+}
+
+func runHelp(c *client, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		printCommands(out)
+		return nil
+	}
+	cmd := lookup(args[0])
+	if cmd == nil {
+		return &usageError{msg: fmt.Sprintf("unknown command %q", args[0])}
+	}
+	fmt.Fprintf(out, "usage: %s %s\n%s\n", cmd.name, cmd.usage, cmd.help)
+	return nil
+}
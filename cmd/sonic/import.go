@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/expectedsh/go-sonic/sonic"
+	"golang.org/x/term"
+)
+
+// maxReportedErrors is the number of invalid and failed records listed in the summary.
+const maxReportedErrors = 10
+
+func runImport(c *client, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "jsonl (or ndjson) or csv, guessed from the file extension by default")
+	bucket := fs.String("bucket", "default", "bucket of the records")
+	bucketField := fs.String("bucket-field", "", "field holding the bucket of a record, overriding -bucket")
+	objectField := fs.String("object-field", "object", "field holding the object")
+	textFields := fs.String("text-field", "text", "comma separated fields holding the text")
+	lang := fs.String("lang", "", "language of the records, eg. eng or none, detected by default")
+	langField := fs.String("lang-field", "", "field holding the language of a record, overriding -lang")
+	delimiter := fs.String("delimiter", ",", "CSV delimiter")
+	batch := fs.Int("batch", 1000, "number of records per bulk push")
+	parallel := fs.Int("parallel", 4, "number of connections pushing a batch")
+	offset := fs.Int("offset", 0, "number of records to skip, to resume a failed import")
+	pos, err := parseArgs(lookup("import"), fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+
+	opts := sonic.ImportOptions{
+		Format:           importFormat(*format, pos[1]),
+		Collection:       pos[0],
+		Bucket:           *bucket,
+		BucketField:      *bucketField,
+		ObjectField:      *objectField,
+		TextFields:       strings.Split(*textFields, ","),
+		Lang:             sonic.Lang(*lang),
+		LangField:        *langField,
+		BatchSize:        *batch,
+		ParallelRoutines: *parallel,
+		Offset:           *offset,
+	}
+	if d := []rune(*delimiter); len(d) > 0 {
+		opts.Comma = d[0]
+	}
+	if opts.Format == "" {
+		return &usageError{lookup("import"), fmt.Sprintf("unknown format %q", *format), ""}
+	}
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		opts.Progress = func(s sonic.ImportSummary) {
+			fmt.Fprintf(os.Stderr, "\rimported %d records, %d invalid, %d failed", s.Pushed, len(s.Invalid), len(s.Failed))
+		}
+	}
+
+	in := io.Reader(os.Stdin)
+	if pos[1] != "-" {
+		f, err := os.Open(pos[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	ingest, err := c.ingest()
+	if err != nil {
+		return err
+	}
+
+	summary, err := sonic.Import(ingest, in, opts)
+	if opts.Progress != nil {
+		fmt.Fprintln(os.Stderr)
+	}
+	printImportSummary(out, summary)
+	if err != nil {
+		return fmt.Errorf("%v\nresume with -offset %d", err, summary.Offset)
+	}
+	return nil
+}
+
+// importFormat return the format of the -format flag, or guessed from the file extension.
+func importFormat(format, path string) sonic.ImportFormat {
+	if format == "" {
+		format = strings.TrimPrefix(filepath.Ext(path), ".")
+		if format == "" {
+			format = "jsonl"
+		}
+	}
+	switch strings.ToLower(format) {
+	case "jsonl", "ndjson", "json":
+		return sonic.ImportJSONLines
+	case "csv":
+		return sonic.ImportCSV
+	}
+	return ""
+}
+
+func printImportSummary(out io.Writer, s sonic.ImportSummary) {
+	rate := 0.0
+	if s.Duration > 0 {
+		rate = float64(s.Pushed) / s.Duration.Seconds()
+	}
+	fmt.Fprintf(out, "records:  %d (%d skipped)\n", s.Records, s.Skipped)
+	fmt.Fprintf(out, "pushed:   %d (%.0f/s)\n", s.Pushed, rate)
+	fmt.Fprintf(out, "invalid:  %d\n", len(s.Invalid))
+	fmt.Fprintf(out, "failed:   %d\n", len(s.Failed))
+	fmt.Fprintf(out, "duration: %s\n", s.Duration.Round(time.Millisecond))
+
+	for n, e := range s.Invalid {
+		if n == maxReportedErrors {
+			fmt.Fprintf(out, "  ... %d more invalid records\n", len(s.Invalid)-n)
+			break
+		}
+		fmt.Fprintf(out, "  invalid record %d: %v\n", e.Record, e.Err)
+	}
+	for n, e := range s.Failed {
+		if n == maxReportedErrors {
+			fmt.Fprintf(out, "  ... %d more failed objects\n", len(s.Failed)-n)
+			break
+		}
+		fmt.Fprintf(out, "  failed object %s: %v\n", e.Object, e.Error)
+	}
+}
@@ -0,0 +1,84 @@
+// Command sonic operate a sonic server from the terminal.
+//
+//	sonic [flags] <command> [arguments]
+//
+// Without command, an interactive shell is started, with history and tab completion.
+// The connection flags default to the SONIC_HOST, SONIC_PORT, SONIC_PASSWORD
+// and SONIC_PASSWORD_FILE environment variables.
+//
+//	sonic push movies general id:1 "Spider man"
+//	sonic query -limit 5 movies general man
+//	sonic -host sonic.internal info
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/expectedsh/go-sonic/sonic"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run execute the command line and return the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("sonic", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	host := fs.String("host", envOr("SONIC_HOST", "localhost"), "sonic host")
+	port := fs.Int("port", envInt("SONIC_PORT", 1491), "sonic port")
+	password := fs.String("password", envOr("SONIC_PASSWORD", "SecretPassword"), "sonic password")
+	passwordFile := fs.String("password-file", os.Getenv("SONIC_PASSWORD_FILE"), "read the password from a file on each connection, instead of -password")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: sonic [flags] [command] [arguments]\n\nflags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(stderr, "\ncommands:\n")
+		printCommands(stderr)
+		fmt.Fprintf(stderr, "\nWithout command, an interactive shell is started.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var opts []sonic.Option
+	if *passwordFile != "" {
+		opts = append(opts, sonic.WithCredentials(sonic.PasswordFile(*passwordFile)))
+	}
+	c := newClient(*host, *port, *password, opts)
+	defer c.close()
+
+	var err error
+	if fs.NArg() == 0 {
+		err = repl(c, stdin, stdout)
+	} else {
+		err = execute(c, fs.Args(), stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "sonic: %v\n", err)
+		var usage *usageError
+		if errors.As(err, &usage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+func envOr(key, def string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const prompt = "sonic> "
+
+// historySize is the number of lines kept in the history file.
+const historySize = 1000
+
+// repl read commands from in until EOF or exit. When in is a terminal the line can be edited,
+// with the history of the previous sessions and tab completion of the commands.
+// Otherwise, eg. a piped script, it stops at the first failing command.
+func repl(c *client, in io.Reader, out io.Writer) error {
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		return interactive(c, f, out)
+	}
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		quit, err := executeLine(c, scanner.Text(), out)
+		if err != nil || quit {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func interactive(c *client, in *os.File, out io.Writer) error {
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	// the commands run with the terminal restored, so ctrl-c sends SIGINT,
+	// which stops a bench and is otherwise ignored by the shell
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{in, out}, prompt)
+	if width, height, err := term.GetSize(int(in.Fd())); err == nil && width > 0 {
+		_ = t.SetSize(width, height)
+	}
+	t.AutoCompleteCallback = func(line string, pos int, key rune) (string, int, bool) {
+		if key != '\t' {
+			return "", 0, false
+		}
+		newLine, newPos, candidates := complete(line, pos)
+		if len(candidates) > 1 {
+			fmt.Fprintln(t, strings.Join(candidates, "  "))
+		}
+		return newLine, newPos, true
+	}
+	history := openHistory()
+	defer history.close()
+	t.History = history
+
+	fmt.Fprintln(t, "Connected to sonic, type help for the commands, exit or ctrl-d to quit.")
+	for {
+		line, err := t.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_ = term.Restore(fd, state)
+		quit, err := executeLine(c, line, t)
+		if _, rawErr := term.MakeRaw(fd); rawErr != nil {
+			return rawErr
+		}
+		if err != nil {
+			fmt.Fprintf(t, "error: %v\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// executeLine run a line of the shell, it reports whether the shell must exit.
+func executeLine(c *client, line string, out io.Writer) (quit bool, err error) {
+	args, err := splitLine(line)
+	if err != nil || len(args) == 0 {
+		return false, err
+	}
+	if args[0] == "exit" || args[0] == "quit" {
+		return true, nil
+	}
+	return false, execute(c, args, out)
+}
+
+// splitLine split a line in arguments, separated by spaces unless they are quoted
+// with single or double quotes. A backslash escape the next character.
+func splitLine(line string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// complete the word before pos, it returns the new line and position,
+// and the candidates when the completion is ambiguous.
+func complete(line string, pos int) (string, int, []string) {
+	start := strings.LastIndexAny(line[:pos], " \t") + 1
+	word := line[start:pos]
+
+	var names []string
+	before := strings.Fields(line[:start])
+	switch {
+	case len(before) == 0:
+		names = append(names, "exit")
+		for _, cmd := range commands {
+			names = append(names, cmd.name)
+		}
+	case len(before) == 1 && before[0] == "help":
+		for _, cmd := range commands {
+			names = append(names, cmd.name)
+		}
+	default:
+		return line, pos, nil
+	}
+
+	var candidates []string
+	for _, name := range names {
+		if strings.HasPrefix(name, word) {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	if len(candidates) == 0 {
+		return line, pos, nil
+	}
+
+	completion := candidates[0]
+	if len(candidates) == 1 {
+		completion += " "
+	} else {
+		for _, c := range candidates[1:] {
+			completion = commonPrefix(completion, c)
+		}
+	}
+	newLine := line[:start] + completion + line[pos:]
+	return newLine, start + len(completion), candidates
+}
+
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:n]
+}
+
+// fileHistory is a term.History saved in ~/.sonic_history, so it is kept between sessions.
+type fileHistory struct {
+	// entries are ordered from the oldest to the most recent
+	entries []string
+	file    *os.File
+}
+
+// openHistory load the history file, the history is only kept in memory if it can't be opened.
+func openHistory() *fileHistory {
+	h := &fileHistory{}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return h
+	}
+	path := filepath.Join(home, ".sonic_history")
+	if b, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if line != "" {
+				h.entries = append(h.entries, line)
+			}
+		}
+	}
+	if len(h.entries) > historySize {
+		h.entries = h.entries[len(h.entries)-historySize:]
+		// rewrite the file so it doesn't grow forever
+		_ = os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0600)
+	}
+	h.file, _ = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	return h
+}
+
+func (h *fileHistory) Add(entry string) {
+	entry = strings.TrimSpace(entry)
+	if entry == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > historySize {
+		h.entries = h.entries[1:]
+	}
+	if h.file != nil {
+		_, _ = fmt.Fprintln(h.file, entry)
+	}
+}
+
+func (h *fileHistory) Len() int {
+	return len(h.entries)
+}
+
+func (h *fileHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+func (h *fileHistory) close() {
+	if h.file != nil {
+		_ = h.file.Close()
+	}
+}
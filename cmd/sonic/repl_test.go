@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line string
+		args []string
+	}{
+		{`push movies general id:1 "Spider man"`, []string{"push", "movies", "general", "id:1", "Spider man"}},
+		{`  query  movies 'it\'s' `, nil},
+		{`query movies general it\'s`, []string{"query", "movies", "general", "it's"}},
+		{`push m g o "say \"hi\""`, []string{"push", "m", "g", "o", `say "hi"`}},
+		{`push m g o ""`, []string{"push", "m", "g", "o", ""}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		args, err := splitLine(tt.line)
+		if tt.args == nil && tt.line != "" {
+			if err == nil {
+				t.Errorf("%q: expected an error, got %q", tt.line, args)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%q: expected %q, got %q %v", tt.line, tt.args, args, err)
+		}
+	}
+}
+
+func TestComplete(t *testing.T) {
+	tests := []struct {
+		line       string
+		newLine    string
+		candidates []string
+	}{
+		{"que", "query ", []string{"query"}},
+		{"p", "p", []string{"ping", "pop", "push"}},
+		{"pu", "push ", []string{"push"}},
+		{"help fl", "help flush ", []string{"flush"}},
+		{"query mov", "query mov", nil},
+	}
+	for _, tt := range tests {
+		newLine, pos, candidates := complete(tt.line, len(tt.line))
+		if newLine != tt.newLine || pos != len(tt.newLine) || !reflect.DeepEqual(candidates, tt.candidates) {
+			t.Errorf("%q: expected %q %v, got %q %d %v", tt.line, tt.newLine, tt.candidates, newLine, pos, candidates)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/expectedsh/go-sonic/contrib/sonicotel
+
+go 1.23.0
+
+require (
+	github.com/expectedsh/go-sonic v0.0.0
+	go.opentelemetry.io/otel v1.35.0
+	go.opentelemetry.io/otel/trace v1.35.0
+)
+
+replace github.com/expectedsh/go-sonic => ../..
@@ -0,0 +1,50 @@
+// Package sonicotel trace the commands sent by go-sonic channels with OpenTelemetry.
+//
+//	tracer := otel.Tracer("github.com/expectedsh/go-sonic")
+//	search, err := sonic.NewSearch("localhost", 1491, "SecretPassword", sonic.WithHooks(sonicotel.Hook(tracer)))
+//
+// The channels don't take a context, so the spans have no parent.
+package sonicotel
+
+import (
+	"context"
+
+	"github.com/expectedsh/go-sonic/sonic"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Hook return a hook creating a client span for every command.
+func Hook(tracer trace.Tracer) sonic.Hook {
+	return func(e sonic.CommandEvent) func(sonic.CommandEvent) {
+		attrs := []attribute.KeyValue{
+			attribute.String("db.system", "sonic"),
+			attribute.String("db.operation.name", e.Command),
+			attribute.String("sonic.channel", string(e.Channel)),
+		}
+		if e.Collection != "" {
+			attrs = append(attrs, attribute.String("db.collection.name", e.Collection))
+		}
+		if e.Bucket != "" {
+			attrs = append(attrs, attribute.String("sonic.bucket", e.Bucket))
+		}
+		_, span := tracer.Start(context.Background(), "sonic "+e.Command,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithTimestamp(e.Start),
+			trace.WithAttributes(attrs...),
+		)
+
+		return func(e sonic.CommandEvent) {
+			span.SetAttributes(
+				attribute.Int("sonic.bytes_sent", e.BytesSent),
+				attribute.Int("sonic.bytes_received", e.BytesReceived),
+			)
+			if e.Err != nil {
+				span.RecordError(e.Err)
+				span.SetStatus(codes.Error, e.Err.Error())
+			}
+			span.End(trace.WithTimestamp(e.Start.Add(e.Duration)))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Package sonicprom export Prometheus metrics for the commands sent by go-sonic channels.
+//
+//	metrics := sonicprom.New("myapp")
+//	prometheus.MustRegister(metrics)
+//	search, err := sonic.NewSearch("localhost", 1491, "SecretPassword", sonic.WithHooks(metrics.Hook()))
+package sonicprom
+
+import (
+	"github.com/expectedsh/go-sonic/sonic"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var labels = []string{"channel", "command", "collection", "status"}
+
+// Metrics is a prometheus.Collector of the commands seen by its hook.
+type Metrics struct {
+	commands      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	bytesSent     *prometheus.CounterVec
+	bytesReceived *prometheus.CounterVec
+}
+
+// New create the metrics, namespace is prepended to their names.
+func New(namespace string) *Metrics {
+	return &Metrics{
+		commands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sonic",
+			Name:      "commands_total",
+			Help:      "Number of commands sent to sonic.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "sonic",
+			Name:      "command_duration_seconds",
+			Help:      "Duration of the commands sent to sonic.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, labels),
+		bytesSent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sonic",
+			Name:      "sent_bytes_total",
+			Help:      "Bytes of the commands sent to sonic.",
+		}, labels),
+		bytesReceived: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "sonic",
+			Name:      "received_bytes_total",
+			Help:      "Bytes of the responses received from sonic.",
+		}, labels),
+	}
+}
+
+// Hook return the hook to give to sonic.WithHooks.
+func (m *Metrics) Hook() sonic.Hook {
+	return func(sonic.CommandEvent) func(sonic.CommandEvent) {
+		return m.observe
+	}
+}
+
+func (m *Metrics) observe(e sonic.CommandEvent) {
+	status := "ok"
+	if e.Err != nil {
+		status = "error"
+	}
+	values := []string{string(e.Channel), e.Command, e.Collection, status}
+	m.commands.WithLabelValues(values...).Inc()
+	m.duration.WithLabelValues(values...).Observe(e.Duration.Seconds())
+	m.bytesSent.WithLabelValues(values...).Add(float64(e.BytesSent))
+	m.bytesReceived.WithLabelValues(values...).Add(float64(e.BytesReceived))
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.commands.Describe(ch)
+	m.duration.Describe(ch)
+	m.bytesSent.Describe(ch)
+	m.bytesReceived.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.commands.Collect(ch)
+	m.duration.Collect(ch)
+	m.bytesSent.Collect(ch)
+	m.bytesReceived.Collect(ch)
+}
@@ -0,0 +1,29 @@
+package sonicprom
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/expectedsh/go-sonic/sonic"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	m := New("test")
+	hook := m.Hook()
+	for _, err := range []error{nil, nil, errors.New("down")} {
+		e := sonic.CommandEvent{Channel: sonic.Search, Command: "QUERY", Collection: "movies", Duration: time.Millisecond, BytesSent: 10, Err: err}
+		hook(e)(e)
+	}
+
+	if n := testutil.ToFloat64(m.commands.WithLabelValues("search", "QUERY", "movies", "ok")); n != 2 {
+		t.Errorf("expected 2 successful queries, got %v", n)
+	}
+	if n := testutil.ToFloat64(m.bytesSent.WithLabelValues("search", "QUERY", "movies", "error")); n != 10 {
+		t.Errorf("expected 10 bytes sent by the failed query, got %v", n)
+	}
+	if n := testutil.CollectAndCount(m); n != 8 {
+		t.Errorf("expected 8 series, got %d", n)
+	}
+}
@@ -1,3 +1,3 @@
 module github.com/expectedsh/go-sonic
 
-go 1.14
+go 1.18
@@ -0,0 +1,163 @@
+package sonic
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// AutocompleteOptions configure an Autocomplete.
+type AutocompleteOptions struct {
+	// SuggestLimit is the number of completions of the trailing word, default to 5.
+	SuggestLimit int
+
+	// QueryLimit is the number of matching objects, default to 10.
+	QueryLimit int
+
+	// Lang of the queries.
+	Lang Lang
+
+	// CacheSize is the number of inputs kept in cache, default to 256.
+	CacheSize int
+
+	// CacheTTL is the lifetime of cached completions, 0 keep them until evicted.
+	CacheTTL time.Duration
+}
+
+// Completion is the result of an Autocomplete for an input.
+type Completion struct {
+	Input string
+
+	// Completions are the input with its trailing word completed, best first.
+	Completions []string
+
+	// Objects match the completed words and the best completion of the trailing word.
+	Objects []string
+}
+
+// Autocomplete implement search-as-you-type on top of a search channel:
+// the trailing partial word is completed with SUGGEST while the objects
+// are queried with the words already typed. Recent inputs are cached.
+// Autocomplete is safe for concurrent use, queries are serialized on the channel.
+type Autocomplete struct {
+	collection, bucket string
+	opts               AutocompleteOptions
+
+	mu     sync.Mutex
+	search Searchable
+	cache  *lru
+}
+
+// NewAutocomplete create an Autocomplete for a bucket.
+func NewAutocomplete(s Searchable, collection, bucket string, opts AutocompleteOptions) *Autocomplete {
+	if opts.SuggestLimit <= 0 {
+		opts.SuggestLimit = 5
+	}
+	if opts.QueryLimit <= 0 {
+		opts.QueryLimit = 10
+	}
+	if opts.CacheSize <= 0 {
+		opts.CacheSize = 256
+	}
+	return &Autocomplete{
+		collection: collection,
+		bucket:     bucket,
+		opts:       opts,
+		search:     s,
+		cache:      newLRU(opts.CacheSize, opts.CacheTTL),
+	}
+}
+
+// Complete return the completions and matching objects of an input.
+// If the input ends with a space, the last word is considered complete and only the objects are queried.
+func (a *Autocomplete) Complete(input string) (*Completion, error) {
+	words := strings.Fields(strings.ToLower(input))
+	trailing := ""
+	last, _ := utf8.DecodeLastRuneInString(input)
+	if len(words) > 0 && !unicode.IsSpace(last) {
+		trailing = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	key := strings.Join(words, " ") + "\x00" + trailing
+	if c, ok := a.cache.get(key); ok {
+		return c.(*Completion).copy(input), nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	completion := &Completion{Input: input, Completions: []string{}, Objects: []string{}}
+	terms := words
+	if trailing != "" {
+		suggestions, err := a.search.Suggest(a.collection, a.bucket, trailing, a.opts.SuggestLimit)
+		if err != nil {
+			return nil, err
+		}
+		prefix := strings.Join(words, " ")
+		if prefix != "" {
+			prefix += " "
+		}
+		for _, s := range suggestions {
+			completion.Completions = append(completion.Completions, prefix+s)
+		}
+
+		best := trailing
+		if len(suggestions) > 0 {
+			best = suggestions[0]
+		}
+		terms = append(terms[:len(terms):len(terms)], best)
+	}
+
+	if len(terms) > 0 {
+		objects, err := a.search.Query(a.collection, a.bucket, strings.Join(terms, " "), a.opts.QueryLimit, 0, a.opts.Lang)
+		if err != nil {
+			return nil, err
+		}
+		completion.Objects = objects
+	}
+
+	a.cache.set(key, completion)
+	return completion.copy(input), nil
+}
+
+// copy return a copy of a cached completion, so callers can't alter the cache.
+func (c *Completion) copy(input string) *Completion {
+	return &Completion{
+		Input:       input,
+		Completions: append([]string{}, c.Completions...),
+		Objects:     append([]string{}, c.Objects...),
+	}
+}
+
+// Debounce return a function to call on every keystroke: the completion is only
+// computed once no input was received for delay, then given to fn.
+// Results of outdated inputs are discarded.
+func (a *Autocomplete) Debounce(delay time.Duration, fn func(*Completion, error)) func(input string) {
+	var mu sync.Mutex
+	var timer *time.Timer
+	var generation uint64
+
+	return func(input string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		generation++
+		current := generation
+		if timer != nil {
+			timer.Stop()
+		}
+		timer = time.AfterFunc(delay, func() {
+			c, err := a.Complete(input)
+
+			mu.Lock()
+			outdated := current != generation
+			mu.Unlock()
+			if !outdated {
+				fn(c, err)
+			}
+		})
+	}
+}
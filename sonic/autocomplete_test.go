@@ -0,0 +1,70 @@
+package sonic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAutocomplete(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		switch {
+		case strings.HasPrefix(cmd, `SUGGEST movies general "wa"`):
+			return []string{"PENDING q1", "EVENT SUGGEST q1 wars wall"}
+		case strings.HasPrefix(cmd, `QUERY movies general "star wars"`):
+			return []string{"PENDING q1", "EVENT QUERY q1 id:1 id:2"}
+		case strings.HasPrefix(cmd, `QUERY movies general "star"`):
+			return []string{"PENDING q1", "EVENT QUERY q1 id:1 id:2 id:3"}
+		}
+		return []string{"PENDING q1", "EVENT QUERY q1"}
+	})
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+
+	a := NewAutocomplete(search, "movies", "general", AutocompleteOptions{})
+	want := &Completion{
+		Input:       "Star Wa",
+		Completions: []string{"star wars", "star wall"},
+		Objects:     []string{"id:1", "id:2"},
+	}
+	for n := 0; n < 2; n++ {
+		c, err := a.Complete("Star Wa")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(c, want) {
+			t.Errorf("got %+v, want %+v", c, want)
+		}
+	}
+	if received := server.received(); len(received) != 2 {
+		t.Errorf("expected the second completion to be cached, got commands %v", received)
+	}
+
+	c, err := a.Complete("star ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Completions) != 0 || len(c.Objects) != 3 {
+		t.Errorf("unexpected completion of a complete word %+v", c)
+	}
+
+	results := make(chan *Completion, 3)
+	debounced := a.Debounce(20*time.Millisecond, func(c *Completion, err error) {
+		results <- c
+	})
+	debounced("st")
+	debounced("sta")
+	debounced("Star Wa")
+	if c := <-results; c.Input != "Star Wa" {
+		t.Errorf("expected only the last input to be completed, got %q", c.Input)
+	}
+	select {
+	case c := <-results:
+		t.Errorf("unexpected completion of %q", c.Input)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
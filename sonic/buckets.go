@@ -0,0 +1,117 @@
+package sonic
+
+import (
+	"sort"
+	"sync"
+)
+
+// MergeStrategy define how the results of several buckets are merged.
+type MergeStrategy int
+
+const (
+	// MergeInterleave order the results by their relative rank in their bucket,
+	// so buckets with few results don't push their last ones ahead of the others' best.
+	MergeInterleave MergeStrategy = iota
+
+	// MergeRoundRobin take one result of each bucket in turn.
+	MergeRoundRobin
+
+	// MergePriority return all the results of the first bucket, then the second, etc.
+	MergePriority
+)
+
+// BucketResult is an object found by a multi-bucket search.
+type BucketResult struct {
+	Bucket, Object string
+}
+
+// QueryBucketError represent an error for a given bucket in a multi-bucket search.
+type QueryBucketError struct {
+	Bucket string
+	Error  error
+}
+
+func (s searchChannel) QueryBuckets(collection string, buckets []string, terms string, limit int, lang Lang, parallelRoutines int, strategy MergeStrategy) (results []BucketResult, errs []QueryBucketError) {
+	if parallelRoutines <= 0 {
+		parallelRoutines = 1
+	}
+
+	perBucket := make([][]string, len(buckets))
+	errs = make([]QueryBucketError, 0)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallelRoutines)
+
+	for n, bucket := range buckets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(n int, bucket string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			d, err := s.pool.get()
+			if err == nil {
+				perBucket[n], err = searchChannel{driver: d}.Query(collection, bucket, terms, limit, 0, lang)
+				s.pool.put(d, err)
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, QueryBucketError{bucket, err})
+				mu.Unlock()
+			}
+		}(n, bucket)
+	}
+	wg.Wait()
+
+	results = mergeBucketResults(buckets, perBucket, strategy)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, errs
+}
+
+func mergeBucketResults(buckets []string, perBucket [][]string, strategy MergeStrategy) []BucketResult {
+	results := make([]BucketResult, 0)
+	switch strategy {
+	case MergePriority:
+		for n, objects := range perBucket {
+			for _, object := range objects {
+				results = append(results, BucketResult{buckets[n], object})
+			}
+		}
+
+	case MergeRoundRobin:
+		for rank := 0; ; rank++ {
+			found := false
+			for n, objects := range perBucket {
+				if rank < len(objects) {
+					results = append(results, BucketResult{buckets[n], objects[rank]})
+					found = true
+				}
+			}
+			if !found {
+				break
+			}
+		}
+
+	default:
+		type ranked struct {
+			BucketResult
+			score float64
+		}
+		all := make([]ranked, 0)
+		for n, objects := range perBucket {
+			for rank, object := range objects {
+				all = append(all, ranked{BucketResult{buckets[n], object}, float64(rank) / float64(len(objects))})
+			}
+		}
+		// stable, so ties keep the order of buckets
+		sort.SliceStable(all, func(i, j int) bool {
+			return all[i].score < all[j].score
+		})
+		for _, r := range all {
+			results = append(results, r.BucketResult)
+		}
+	}
+	return results
+}
@@ -0,0 +1,84 @@
+package sonic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchChannel_QueryBuckets(t *testing.T) {
+	replies := queryReplies(map[string]string{
+		"movies tenant-1": "a b c d",
+		"movies tenant-2": "e f",
+	})
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		if strings.Contains(cmd, " broken ") {
+			return []string{"ERR query_failed"}
+		}
+		return replies(cmd)
+	})
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+
+	buckets := []string{"tenant-1", "broken", "tenant-2"}
+	tests := []struct {
+		strategy MergeStrategy
+		want     string
+	}{
+		{MergeInterleave, "tenant-1/a tenant-2/e tenant-1/b tenant-1/c tenant-2/f tenant-1/d"},
+		{MergeRoundRobin, "tenant-1/a tenant-2/e tenant-1/b tenant-2/f tenant-1/c tenant-1/d"},
+		{MergePriority, "tenant-1/a tenant-1/b tenant-1/c tenant-1/d tenant-2/e tenant-2/f"},
+	}
+	for _, tt := range tests {
+		results, errs := search.QueryBuckets("movies", buckets, "man", 10, LangNone, 2, tt.strategy)
+		var got []string
+		for _, r := range results {
+			got = append(got, r.Bucket+"/"+r.Object)
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("strategy %d: got %v, want %v", tt.strategy, got, tt.want)
+		}
+		if len(errs) != 1 || errs[0].Bucket != "broken" {
+			t.Errorf("strategy %d: unexpected errors %v", tt.strategy, errs)
+		}
+	}
+
+	results, _ := search.QueryBuckets("movies", buckets, "man", 2, LangNone, 2, MergePriority)
+	if want := []BucketResult{{"tenant-1", "a"}, {"tenant-1", "b"}}; !reflect.DeepEqual(results, want) {
+		t.Errorf("got %v, want %v", results, want)
+	}
+
+	// the ERR replies don't break the pooled connection, it is reused
+	connections := func() int {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.conns)
+	}
+	_, _ = search.QueryBuckets("movies", buckets, "man", 10, LangNone, 1, MergePriority)
+	before := connections()
+	for n := 0; n < 3; n++ {
+		_, _ = search.QueryBuckets("movies", buckets, "man", 10, LangNone, 1, MergePriority)
+	}
+	if after := connections(); after != before {
+		t.Errorf("expected the pooled connection to be reused, %d connections were opened", after-before)
+	}
+
+	// no connection is opened once the channel is closed
+	_ = search.Quit()
+	before = connections()
+	_, errs := search.QueryBuckets("movies", buckets, "man", 10, LangNone, 2, MergePriority)
+	if len(errs) != len(buckets) {
+		t.Fatalf("expected every bucket to fail, got %v", errs)
+	}
+	for _, e := range errs {
+		if e.Error != ErrClosed {
+			t.Errorf("%s: got error %v, want %v", e.Bucket, e.Error, ErrClosed)
+		}
+	}
+	if after := connections(); after != before {
+		t.Errorf("expected no connection to be opened after Quit, %d were opened", after-before)
+	}
+}
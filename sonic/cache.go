@@ -0,0 +1,151 @@
+package sonic
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Cache is a query result cache shared by search and ingest channels.
+// Results of Query and Suggest go through the cache, and are invalidated
+// per collection and bucket when PUSH, POP or FLUSH commands are sent
+// through an ingest channel wrapped by the same cache.
+//
+//	cache := sonic.NewCache(1024, time.Minute)
+//	search = cache.Searchable(search)
+//	ingester = cache.Ingestable(ingester)
+//
+// Changes made by other clients are only seen once the entries expire.
+type Cache struct {
+	// version is incremented by every invalidation, so results of queries
+	// running during an invalidation aren't cached
+	version uint64
+
+	lru *lru
+}
+
+// NewCache create a cache of size entries, expiring after ttl if ttl > 0.
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{lru: newLRU(size, ttl)}
+}
+
+// Searchable wrap a search channel to cache its Query and Suggest results.
+// Other methods aren't cached.
+func (c *Cache) Searchable(s Searchable) Searchable {
+	return cachedSearch{Searchable: s, cache: c}
+}
+
+// Ingestable wrap an ingest channel to invalidate the cache when it alters the index.
+func (c *Cache) Ingestable(i Ingestable) Ingestable {
+	return cachedIngest{Ingestable: i, cache: c}
+}
+
+// Invalidate remove the cached results of a bucket, or of a whole collection if bucket is empty.
+func (c *Cache) Invalidate(collection, bucket string) {
+	atomic.AddUint64(&c.version, 1)
+	c.lru.removeTag(cacheTag(collection, bucket))
+}
+
+// Len return the number of cached results.
+func (c *Cache) Len() int {
+	return c.lru.len()
+}
+
+// cacheTag return the tag of the results of a bucket, or of a collection if bucket is empty.
+func cacheTag(collection, bucket string) string {
+	return collection + "\x00" + bucket
+}
+
+// fetch return the cached results of key or call fn and cache its results,
+// tagged with their collection and bucket for Invalidate.
+func (c *Cache) fetch(collection, bucket, key string, fn func() ([]string, error)) ([]string, error) {
+	if results, ok := c.lru.get(key); ok {
+		return append([]string{}, results.([]string)...), nil
+	}
+
+	version := atomic.LoadUint64(&c.version)
+	results, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	if atomic.LoadUint64(&c.version) == version {
+		c.lru.set(key, append([]string{}, results...), cacheTag(collection, ""), cacheTag(collection, bucket))
+	}
+	return results, nil
+}
+
+type cachedSearch struct {
+	Searchable
+	cache *Cache
+}
+
+func (s cachedSearch) Query(collection, bucket, terms string, limit, offset int, lang Lang) (results []string, err error) {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%d\x00%d\x00%s", collection, bucket, query, terms, limit, offset, lang)
+	return s.cache.fetch(collection, bucket, key, func() ([]string, error) {
+		return s.Searchable.Query(collection, bucket, terms, limit, offset, lang)
+	})
+}
+
+func (s cachedSearch) Suggest(collection, bucket, word string, limit int) (results []string, err error) {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%d", collection, bucket, suggest, word, limit)
+	return s.cache.fetch(collection, bucket, key, func() ([]string, error) {
+		return s.Searchable.Suggest(collection, bucket, word, limit)
+	})
+}
+
+// cachedIngest invalidate the cache after every command altering the index,
+// even failed ones since they may have partially succeeded.
+type cachedIngest struct {
+	Ingestable
+	cache *Cache
+}
+
+func (i cachedIngest) Push(collection, bucket, object, text string, lang Lang) (err error) {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.Push(collection, bucket, object, text, lang)
+}
+
+func (i cachedIngest) BulkPush(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.BulkPush(collection, bucket, parallelRoutines, records, lang)
+}
+
+func (i cachedIngest) Pop(collection, bucket, object, text string) (err error) {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.Pop(collection, bucket, object, text)
+}
+
+func (i cachedIngest) BulkPop(collection, bucket string, parallelRoutines int, records []IngestBulkRecord) []IngestBulkError {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.BulkPop(collection, bucket, parallelRoutines, records)
+}
+
+func (i cachedIngest) FlushCollection(collection string) (err error) {
+	defer i.cache.Invalidate(collection, "")
+	return i.Ingestable.FlushCollection(collection)
+}
+
+func (i cachedIngest) FlushBucket(collection, bucket string) (err error) {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.FlushBucket(collection, bucket)
+}
+
+func (i cachedIngest) FlushObject(collection, bucket, object string) (err error) {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.FlushObject(collection, bucket, object)
+}
+
+func (i cachedIngest) Replace(collection, bucket, object, text string, lang Lang) (err error) {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.Replace(collection, bucket, object, text, lang)
+}
+
+func (i cachedIngest) BulkReplace(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.BulkReplace(collection, bucket, parallelRoutines, records, lang)
+}
+
+func (i cachedIngest) Update(collection, bucket, object, oldText, newText string, lang Lang) (err error) {
+	defer i.cache.Invalidate(collection, bucket)
+	return i.Ingestable.Update(collection, bucket, object, oldText, newText, lang)
+}
@@ -0,0 +1,89 @@
+package sonic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(map[string]string{
+		"movies general": "id:1",
+		"movies other":   "id:2",
+	}))
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	cache := NewCache(10, time.Minute)
+	search, ing = cache.Searchable(search), cache.Ingestable(ing)
+
+	queries := func() {
+		for _, bucket := range []string{"general", "other"} {
+			if _, err := search.Query("movies", bucket, "man", 10, 0, LangNone); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	queries()
+	queries()
+	if n := len(server.received()); n != 2 {
+		t.Fatalf("expected 2 queries to be sent, got %d", n)
+	}
+
+	if err := ing.Push("movies", "general", "id:3", "Spider man", LangNone); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("expected only the other bucket to stay cached, got %d entries", cache.Len())
+	}
+	queries()
+	if n := len(server.received()); n != 4 {
+		t.Errorf("expected the general bucket to be queried again, got %d commands", n)
+	}
+
+	if err := ing.FlushCollection("movies"); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected the collection to be invalidated, got %d entries", cache.Len())
+	}
+	if len(cache.lru.tagged) != 0 {
+		t.Errorf("expected the tag index to be emptied, got %v", cache.lru.tagged)
+	}
+}
+
+func TestLRU_RemoveTag(t *testing.T) {
+	c := newLRU(2, 0)
+	c.set("a", 1, "x")
+	c.set("b", 2, "x", "y")
+	c.set("c", 3, "y")
+
+	// a is evicted, its tag must not remove anything afterwards
+	if _, ok := c.get("a"); ok {
+		t.Fatal("expected a to be evicted")
+	}
+	c.removeTag("x")
+	if _, ok := c.get("b"); ok {
+		t.Error("expected b to be removed with tag x")
+	}
+	if _, ok := c.get("c"); !ok {
+		t.Error("expected c to stay cached")
+	}
+
+	// replacing an entry replace its tags
+	c.set("c", 4)
+	c.removeTag("y")
+	if v, ok := c.get("c"); !ok || v != 4 {
+		t.Errorf("expected c to stay cached, got %v", v)
+	}
+	if len(c.tagged) != 0 {
+		t.Errorf("expected the tag index to be emptied, got %v", c.tagged)
+	}
+}
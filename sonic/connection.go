@@ -20,9 +20,14 @@ type connection struct {
 }
 
 func newConnection(d *driver) (*connection, error) {
+	password, err := d.password()
+	if err != nil {
+		return nil, fmt.Errorf("sonic credentials: %w", err)
+	}
+
 	c := &connection{}
 	c.close()
-	conn, err := net.Dial("tcp", fmt.Sprintf("%s:%d", d.Host, d.Port))
+	conn, err := net.Dial("tcp", net.JoinHostPort(d.Host, strconv.Itoa(d.Port)))
 	if err != nil {
 		return nil, err
 	}
@@ -31,16 +36,37 @@ func newConnection(d *driver) (*connection, error) {
 	c.conn = conn
 	c.reader = bufio.NewReader(c.conn)
 
-	err = c.write(fmt.Sprintf("START %s %s", d.channel, d.Password))
+	err = c.start(d.channel, password)
 	if err != nil {
+		c.close()
 		return nil, err
 	}
-	_, err = c.read()
-	_, err = c.read()
+	return c, nil
+}
+
+// start send START, sonic answer STARTED or ENDED with the reason, eg. authentication_failed.
+func (c *connection) start(channel Channel, password string) error {
+	// should get CONNECTED
+	_, err := c.read()
 	if err != nil {
-		return nil, err
+		return err
 	}
-	return c, nil
+	err = c.write(fmt.Sprintf("START %s %s", channel, password))
+	if err != nil {
+		return err
+	}
+	line, err := c.read()
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(line, "ENDED ") {
+		reason := strings.TrimPrefix(line, "ENDED ")
+		if strings.Contains(reason, "authentication") {
+			return fmt.Errorf("%w: %s", ErrAuthFailed, reason)
+		}
+		return fmt.Errorf("sonic refused to start %s channel: %s", channel, reason)
+	}
+	return nil
 }
 
 func (c *connection) read() (string, error) {
@@ -64,7 +90,7 @@ func (c *connection) read() (string, error) {
 
 	str := buffer.String()
 	if strings.HasPrefix(str, "ERR ") {
-		return "", errors.New(str[4:])
+		return "", &ServerError{Message: str[4:]}
 	}
 	if strings.HasPrefix(str, "STARTED ") {
 
@@ -3,6 +3,7 @@ package sonic
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // ErrActionName is throw when the action is invalid.
@@ -14,6 +15,10 @@ type Controllable interface {
 	// Command syntax TRIGGER [<action>]?.
 	Trigger(action Action) (err error)
 
+	// Info return the statistics of the server, eg. uptime or clients_connected.
+	// Command syntax INFO.
+	Info() (info map[string]string, err error)
+
 	// Quit refer to the Base interface
 	Quit() (err error)
 
@@ -28,13 +33,8 @@ type controlChannel struct {
 
 // NewControl create a new driver instance with a controlChannel instance.
 // Only way to get a Controllable implementation.
-func NewControl(host string, port int, password string) (Controllable, error) {
-	driver := &driver{
-		Host:     host,
-		Port:     port,
-		Password: password,
-		channel:  Control,
-	}
+func NewControl(host string, port int, password string, opts ...Option) (Controllable, error) {
+	driver := newDriver(host, port, password, Control, opts)
 	err := driver.Connect()
 	if err != nil {
 		return nil, err
@@ -48,15 +48,24 @@ func (c controlChannel) Trigger(action Action) (err error) {
 	if !IsActionValid(action) {
 		return ErrActionName
 	}
-	err = c.write(fmt.Sprintf("TRIGGER %s", action))
-	if err != nil {
-		return err
-	}
-
 	// should get OK
-	_, err = c.read()
+	_, err = c.exec(fmt.Sprintf("TRIGGER %s", action), 1)
+	return err
+}
+
+func (c controlChannel) Info() (info map[string]string, err error) {
+	// should get RESULT uptime(<value>) clients_connected(<value>) ...
+	r, err := c.exec("INFO", 1)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	info = make(map[string]string)
+	for _, field := range strings.Fields(strings.TrimPrefix(r, "RESULT ")) {
+		n := strings.IndexByte(field, '(')
+		if n <= 0 || !strings.HasSuffix(field, ")") {
+			continue
+		}
+		info[field[:n]] = field[n+1 : len(field)-1]
 	}
-	return nil
+	return info, nil
 }
@@ -0,0 +1,26 @@
+package sonic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestControlChannel_Info(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		return []string{"RESULT uptime(84) clients_connected(2) commands_total(31) kv_open_count(1)"}
+	})
+	control, err := NewControl("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer control.Quit()
+
+	info, err := control.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{"uptime": "84", "clients_connected": "2", "commands_total": "31", "kv_open_count": "1"}
+	if !reflect.DeepEqual(info, expected) {
+		t.Errorf("expected %v, got %v", expected, info)
+	}
+}
@@ -0,0 +1,54 @@
+package sonic
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// ErrAuthFailed is throw when sonic rejects the password sent at START.
+var ErrAuthFailed = errors.New("sonic authentication failed")
+
+// CredentialsProvider give the password sent to sonic, it is consulted each time
+// a channel connects or reconnects, so the password can rotate without restarting.
+type CredentialsProvider interface {
+	Password() (string, error)
+}
+
+// CredentialsFunc is a function implementing CredentialsProvider,
+// eg. to fetch the password from a secret store.
+type CredentialsFunc func() (string, error)
+
+// Password call f.
+func (f CredentialsFunc) Password() (string, error) {
+	return f()
+}
+
+// PasswordFile is a CredentialsProvider reading the password from a file,
+// eg. a mounted secret. Surrounding spaces and newlines are trimmed.
+type PasswordFile string
+
+// Password read the file.
+func (path PasswordFile) Password() (string, error) {
+	b, err := os.ReadFile(string(path))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// WithCredentials make the channel get its password from provider on each connection,
+// the password given to NewSearch, NewIngester or NewControl is ignored.
+func WithCredentials(provider CredentialsProvider) Option {
+	return func(d *driver) {
+		d.credentials = provider
+	}
+}
+
+// password return the password to send at START.
+func (c *driver) password() (string, error) {
+	if c.credentials == nil {
+		return c.Password, nil
+	}
+	return c.credentials.Password()
+}
@@ -0,0 +1,55 @@
+package sonic
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCredentials(t *testing.T) {
+	server := newFakeServer(t, 20000, func(string) []string { return []string{"OK"} })
+	server.setPassword("first")
+
+	if _, err := NewIngester("127.0.0.1", server.port, "wrong"); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected an authentication error, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(path, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ing, err := NewIngester("127.0.0.1", server.port, "", WithCredentials(PasswordFile(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	// the password rotates, the open connection stays valid
+	server.setPassword("second")
+	if err := os.WriteFile(path, []byte("second\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ing.Push("movies", "general", "id:1", "Batman", LangNone); err != nil {
+		t.Fatal(err)
+	}
+
+	// the new password is read when reconnecting
+	server.dropConnections()
+	_ = ing.Ping()
+	if err := ing.Ping(); err != nil {
+		t.Fatalf("expected to reconnect with the new password, got %v", err)
+	}
+
+	server.setPassword("third")
+	server.dropConnections()
+	_ = ing.Ping()
+	if err := ing.Ping(); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("expected an authentication error on reconnect, got %v", err)
+	}
+
+	failing := CredentialsFunc(func() (string, error) { return "", errors.New("vault is sealed") })
+	if _, err := NewIngester("127.0.0.1", server.port, "", WithCredentials(failing)); err == nil || err.Error() != "sonic credentials: vault is sealed" {
+		t.Errorf("expected the provider error, got %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package sonic
 
 import (
 	"errors"
+	"sync"
+	"time"
 )
 
 var (
@@ -9,11 +11,24 @@ var (
 	// come from the state of the connection.
 	ErrClosed = errors.New("sonic connection is closed")
 
+	// ErrCircuitOpen is throw when the circuit breaker of a channel is open,
+	// the command isn't sent.
+	ErrCircuitOpen = errors.New("sonic circuit breaker is open")
+
 	// ErrChanName is throw when the channel name is not supported
 	// by sonic server.
 	ErrChanName = errors.New("invalid channel name")
 )
 
+// ServerError is an error returned by sonic (ERR response), the connection is still usable.
+type ServerError struct {
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return e.Message
+}
+
 // Base contains commons commands to all channels.
 type Base interface {
 	// Quit stop connection, you can't execute anything after calling this method.
@@ -27,6 +42,26 @@ type Base interface {
 	Ping() error
 }
 
+// Option configure a channel, it is given to NewSearch, NewIngester or NewControl.
+type Option func(d *driver)
+
+// WithPushRollback make Push pop the chunks already indexed when a later chunk fails,
+// so a text split in several commands is either fully indexed or not at all.
+// Beware that popping removes the terms from the object even if they were indexed before the push.
+func WithPushRollback() Option {
+	return func(d *driver) {
+		d.pushRollback = true
+	}
+}
+
+// WithCircuitBreaker make the channel fail fast with ErrCircuitOpen while the breaker is open,
+// see CircuitBreaker. A breaker can be shared by the channels of the same server.
+func WithCircuitBreaker(cb *CircuitBreaker) Option {
+	return func(d *driver) {
+		d.breaker = cb
+	}
+}
+
 type driver struct {
 	Host     string
 	Port     int
@@ -34,6 +69,37 @@ type driver struct {
 
 	channel Channel
 	*connection
+
+	// mu serialize the commands, so a channel can be shared by goroutines
+	// and pinged in background by a HealthChecker
+	mu   sync.Mutex
+	quit bool
+
+	opts         []Option
+	pushRollback bool
+	breaker      *CircuitBreaker
+	hooks        []Hook
+	interceptors []Interceptor
+	credentials  CredentialsProvider
+}
+
+func newDriver(host string, port int, password string, channel Channel, opts []Option) *driver {
+	d := &driver{
+		Host:     host,
+		Port:     port,
+		Password: password,
+		channel:  channel,
+		opts:     opts,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// clone return a new driver, not connected, with the same settings.
+func (c *driver) clone() *driver {
+	return newDriver(c.Host, c.Port, c.Password, c.channel, c.opts)
 }
 
 // Connect open a connection via TCP with the sonic server.
@@ -48,28 +114,132 @@ func (c *driver) Connect() error {
 }
 
 func (c *driver) Quit() error {
-	err := c.write("QUIT")
-	if err != nil {
-		c.close()
-		return err
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.quit {
+		return ErrClosed
+	}
+	c.quit = true
+	if c.isClosed() {
+		return ErrClosed
 	}
 
 	// should get ENDED
-	_, err = c.read()
+	_, err := c.roundTrip("QUIT", 1, nil, nil)
 	c.close()
 	return err
 }
 
-func (c driver) Ping() error {
-	err := c.write("PING")
-	if err != nil {
-		return err
+func (c *driver) Ping() error {
+	// should get PONG
+	_, err := c.exec("PING", 1)
+	return err
+}
+
+// exec send a command and return the last of the lines sonic answer.
+// A connection closed by an error is opened again before sending the command.
+func (c *driver) exec(cmd string, lines int) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.quit {
+		return "", ErrClosed
 	}
 
-	// should get PONG
-	_, err = c.read()
-	if err != nil {
-		return err
+	if len(c.interceptors) == 0 && len(c.hooks) == 0 {
+		return c.breakerRoundTrip(cmd, lines, nil, nil)
+	}
+	send := func(command Command) (string, error) {
+		return c.hookedRoundTrip(command, lines)
+	}
+	return chain(c.interceptors, send)(parseCommand(c.channel, cmd))
+}
+
+// hookedRoundTrip send a command, calling the hooks around.
+func (c *driver) hookedRoundTrip(cmd Command, lines int) (reply string, err error) {
+	if len(c.hooks) == 0 {
+		return c.breakerRoundTrip(cmd.String(), lines, nil, nil)
+	}
+
+	e := newCommandEvent(cmd)
+	done := make([]func(CommandEvent), 0, len(c.hooks))
+	for _, hook := range c.hooks {
+		if fn := hook(e); fn != nil {
+			done = append(done, fn)
+		}
+	}
+	defer func() {
+		e.Duration = time.Since(e.Start)
+		e.Err = err
+		for _, fn := range done {
+			fn(e)
+		}
+	}()
+	return c.breakerRoundTrip(cmd.String(), lines, &e.BytesSent, &e.BytesReceived)
+}
+
+func (c *driver) breakerRoundTrip(cmd string, lines int, sent, received *int) (string, error) {
+	if c.breaker != nil && !c.breaker.Allow() {
+		return "", ErrCircuitOpen
+	}
+
+	reply, err := c.reconnectAndRoundTrip(cmd, lines, sent, received)
+	if c.breaker != nil {
+		c.breaker.Record(isConnectionError(err))
+	}
+	return reply, err
+}
+
+func (c *driver) reconnectAndRoundTrip(cmd string, lines int, sent, received *int) (string, error) {
+	if c.isClosed() {
+		conn, err := newConnection(c)
+		if err != nil {
+			return "", err
+		}
+		c.connection = conn
+	}
+	return c.roundTrip(cmd, lines, sent, received)
+}
+
+// roundTrip write a command and read its replies, c.mu must be held.
+// The bytes written and read are added to sent and received if they aren't nil.
+// The connection is closed if it is broken.
+func (c *driver) roundTrip(cmd string, lines int, sent, received *int) (reply string, err error) {
+	err = c.write(cmd)
+	if err == nil && sent != nil {
+		*sent += len(cmd) + 2
+	}
+	for n := 0; n < lines && err == nil; n++ {
+		reply, err = c.read()
+		if err == nil && received != nil {
+			*received += len(reply) + 2
+		}
+	}
+	if isConnectionError(err) {
+		c.close()
+	}
+	return reply, err
+}
+
+// bufferSize return the buffer size announced by sonic when the connection started, 0 if unknown.
+// It is read under c.mu since a reconnection replace the connection.
+func (c *driver) bufferSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.connection == nil {
+		return 0
+	}
+	return c.cmdMaxBytes
+}
+
+func (c *driver) isClosed() bool {
+	return c.connection == nil || c.closed
+}
+
+// isConnectionError report whether err comes from the connection rather than from sonic.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
 	}
-	return nil
+	var serverErr *ServerError
+	return !errors.As(err, &serverErr)
 }
@@ -0,0 +1,129 @@
+package sonic
+
+import (
+	"sort"
+)
+
+// FieldSeparator separate the collection name from the field name in derived collections.
+const FieldSeparator = "__"
+
+// FieldIndex emulate fields on top of sonic, which has none:
+// each field of a document is pushed in its own collection,
+// derived from the collection name (eg. products__title),
+// so queries can target one or more fields.
+type FieldIndex struct {
+	Collection string
+
+	// Weights of the fields when merging the results of a query, default to 1.
+	Weights map[string]float64
+
+	ingester Ingestable
+	search   Searchable
+}
+
+// NewFieldIndex create a FieldIndex for a collection.
+// ingester is only needed to push and search only to query, the other can be nil.
+func NewFieldIndex(collection string, ingester Ingestable, search Searchable) *FieldIndex {
+	return &FieldIndex{
+		Collection: collection,
+		Weights:    make(map[string]float64),
+		ingester:   ingester,
+		search:     search,
+	}
+}
+
+// FieldCollection return the collection holding a field.
+func (f *FieldIndex) FieldCollection(field string) string {
+	return f.Collection + FieldSeparator + field
+}
+
+// Push index each field of an object in its collection, empty fields are skipped.
+func (f *FieldIndex) Push(bucket, object string, fields map[string]string, lang Lang) error {
+	for _, field := range sortedFields(fields) {
+		if fields[field] == "" {
+			continue
+		}
+		err := f.ingester.Push(f.FieldCollection(field), bucket, object, fields[field], lang)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace replace the indexed data of each given field of an object, see Ingestable.Replace.
+// An empty field is flushed.
+func (f *FieldIndex) Replace(bucket, object string, fields map[string]string, lang Lang) error {
+	for _, field := range sortedFields(fields) {
+		var err error
+		if fields[field] == "" {
+			err = f.ingester.FlushObject(f.FieldCollection(field), bucket, object)
+		} else {
+			err = f.ingester.Replace(f.FieldCollection(field), bucket, object, fields[field], lang)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FlushObject flush the indexed data of an object in the given fields.
+func (f *FieldIndex) FlushObject(bucket, object string, fields ...string) error {
+	for _, field := range fields {
+		err := f.ingester.FlushObject(f.FieldCollection(field), bucket, object)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Query search the terms in each given field and merge the results.
+// Each field contributes weight / (rank + 1) to the score of an object,
+// objects are returned by descending score, without duplicates.
+// limit is applied to each field and to the merged results.
+func (f *FieldIndex) Query(bucket, terms string, fields []string, limit int, lang Lang) (results []string, err error) {
+	scores := make(map[string]float64)
+	var order []string
+	for _, field := range fields {
+		objects, err := f.search.Query(f.FieldCollection(field), bucket, terms, limit, 0, lang)
+		if err != nil {
+			return nil, err
+		}
+
+		weight := f.weight(field)
+		for rank, object := range objects {
+			if _, ok := scores[object]; !ok {
+				order = append(order, object)
+			}
+			scores[object] += weight / float64(rank+1)
+		}
+	}
+
+	// stable, so ties keep the order of fields and ranks
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	if limit > 0 && len(order) > limit {
+		order = order[:limit]
+	}
+	return order, nil
+}
+
+func (f *FieldIndex) weight(field string) float64 {
+	if w, ok := f.Weights[field]; ok {
+		return w
+	}
+	return 1
+}
+
+// sortedFields return the field names in a deterministic order.
+func sortedFields(fields map[string]string) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
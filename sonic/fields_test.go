@@ -0,0 +1,54 @@
+package sonic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// queryReplies reply to QUERY commands with the results of the collection.
+func queryReplies(results map[string]string) func(cmd string) []string {
+	return func(cmd string) []string {
+		fields := strings.Fields(cmd)
+		if fields[0] != "QUERY" && fields[0] != "SUGGEST" && fields[0] != "LIST" {
+			return []string{"OK"}
+		}
+		line := "EVENT " + fields[0] + " q1"
+		if r := results[fields[1]+" "+fields[2]]; r != "" {
+			line += " " + r
+		}
+		return []string{"PENDING q1", line}
+	}
+}
+
+func TestFieldIndex_Query(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(map[string]string{
+		"products__title default": "a b c",
+		"products__body default":  "c d a",
+	}))
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+
+	index := NewFieldIndex("products", nil, search)
+	index.Weights["title"] = 2
+
+	results, err := index.Query("default", "chair", []string{"title", "body"}, 10, LangNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a: 2 + 1/3, c: 2/3 + 1, b: 1, d: 1/2
+	if want := []string{"a", "c", "b", "d"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("got %v, want %v", results, want)
+	}
+
+	results, err = index.Query("default", "chair", []string{"body"}, 2, LangNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"c", "d"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("got %v, want %v", results, want)
+	}
+}
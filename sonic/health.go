@@ -0,0 +1,274 @@
+package sonic
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed let every command through.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fail every command fast, until the cooldown is over.
+	BreakerOpen
+
+	// BreakerHalfOpen let a single trial command through, its result close or open the breaker again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitBreaker stop sending commands to a sonic server which is down.
+// It opens after threshold consecutive connection failures, errors returned
+// by sonic itself (ERR responses) don't count. Once cooldown is elapsed,
+// a single command is let through to probe the server.
+// Attach it to channels with WithCircuitBreaker.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+// NewCircuitBreaker create a closed breaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow report whether a command can be sent.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = BreakerHalfOpen
+		b.trial = true
+		return true
+	case BreakerHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+	}
+	return true
+}
+
+// Record the result of a command let through by Allow.
+func (b *CircuitBreaker) Record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		b.state = BreakerClosed
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.state = BreakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// State return the current state of the breaker.
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// HealthStatus is the health of a channel checked by a HealthChecker.
+type HealthStatus struct {
+	Name                string        `json:"name"`
+	Healthy             bool          `json:"healthy"`
+	Latency             time.Duration `json:"latency"`
+	ConsecutiveFailures int           `json:"consecutive_failures"`
+	LastError           string        `json:"last_error,omitempty"`
+	LastCheck           time.Time     `json:"last_check"`
+	Breaker             string        `json:"breaker,omitempty"`
+}
+
+// HealthChecker ping channels periodically, tracking their latency and failures.
+// A channel is unhealthy after threshold consecutive failed pings.
+// Its ServeHTTP method can be mounted as a /healthz endpoint.
+//
+// Channels can keep being used while they are checked, commands are serialized.
+type HealthChecker struct {
+	interval  time.Duration
+	threshold int
+
+	mu      sync.Mutex
+	targets []*healthTarget
+	started bool
+	stopped bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+type healthTarget struct {
+	channel Base
+	breaker *CircuitBreaker
+	status  HealthStatus
+}
+
+// NewHealthChecker create a HealthChecker pinging every interval once started,
+// default to 10 seconds.
+func NewHealthChecker(interval time.Duration, threshold int) *HealthChecker {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	if threshold <= 0 {
+		threshold = 1
+	}
+	return &HealthChecker{
+		interval:  interval,
+		threshold: threshold,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Add a channel to check. breaker is the one given to the channel with WithCircuitBreaker,
+// if any, its state is reported in the status.
+func (h *HealthChecker) Add(name string, channel Base, breaker *CircuitBreaker) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.targets = append(h.targets, &healthTarget{
+		channel: channel,
+		breaker: breaker,
+		status:  HealthStatus{Name: name, Healthy: true},
+	})
+}
+
+// Start ping the channels in background until Stop is called.
+// It does nothing if the checker is already started or stopped.
+func (h *HealthChecker) Start() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.started || h.stopped {
+		return
+	}
+	h.started = true
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(h.interval)
+		defer ticker.Stop()
+		for {
+			h.Check()
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop the background checks started with Start, the checker can't be started again.
+func (h *HealthChecker) Stop() {
+	h.mu.Lock()
+	if h.stopped {
+		h.mu.Unlock()
+		return
+	}
+	h.stopped = true
+	started := h.started
+	h.mu.Unlock()
+
+	if started {
+		close(h.stop)
+		<-h.done
+	}
+}
+
+// Check ping every channel now.
+func (h *HealthChecker) Check() {
+	h.mu.Lock()
+	targets := append([]*healthTarget(nil), h.targets...)
+	h.mu.Unlock()
+
+	for _, t := range targets {
+		start := time.Now()
+		err := t.channel.Ping()
+		latency := time.Since(start)
+
+		h.mu.Lock()
+		t.status.LastCheck = start
+		if err != nil {
+			t.status.ConsecutiveFailures++
+			t.status.LastError = err.Error()
+		} else {
+			t.status.ConsecutiveFailures = 0
+			t.status.LastError = ""
+			t.status.Latency = latency
+		}
+		t.status.Healthy = t.status.ConsecutiveFailures < h.threshold
+		h.mu.Unlock()
+	}
+}
+
+// Status return the health of every channel.
+func (h *HealthChecker) Status() []HealthStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	statuses := make([]HealthStatus, 0, len(h.targets))
+	for _, t := range h.targets {
+		status := t.status
+		if t.breaker != nil {
+			status.Breaker = t.breaker.State().String()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Healthy report whether every channel is healthy.
+func (h *HealthChecker) Healthy() bool {
+	for _, s := range h.Status() {
+		if !s.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP write the status as JSON, with a 503 status code if a channel is unhealthy.
+func (h *HealthChecker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	statuses := h.Status()
+	code := http.StatusOK
+	for _, s := range statuses {
+		if !s.Healthy {
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(statuses)
+}
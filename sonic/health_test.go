@@ -0,0 +1,109 @@
+package sonic
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	b := NewCircuitBreaker(2, 20*time.Millisecond)
+
+	b.Record(true)
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Fatalf("expected the breaker to stay closed after one failure, got %s", b.State())
+	}
+	b.Record(true)
+	if b.State() != BreakerOpen || b.Allow() {
+		t.Fatalf("expected the breaker to open after two failures, got %s", b.State())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !b.Allow() {
+		t.Fatal("expected a trial command after the cooldown")
+	}
+	if b.State() != BreakerHalfOpen || b.Allow() {
+		t.Fatalf("expected a single trial command, got %s", b.State())
+	}
+	b.Record(true)
+	if b.State() != BreakerOpen {
+		t.Fatalf("expected a failed trial to open the breaker, got %s", b.State())
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	b.Allow()
+	b.Record(false)
+	if b.State() != BreakerClosed || !b.Allow() {
+		t.Fatalf("expected a successful trial to close the breaker, got %s", b.State())
+	}
+}
+
+func TestHealthChecker(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string { return []string{"ERR unknown"} })
+	breaker := NewCircuitBreaker(1, time.Hour)
+	control, err := NewControl("127.0.0.1", server.port, "SecretPassword", WithCircuitBreaker(breaker))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// errors returned by sonic don't open the breaker
+	if err := control.Trigger(Consolidate); !errors.As(err, new(*ServerError)) {
+		t.Fatalf("expected a server error, got %v", err)
+	}
+
+	h := NewHealthChecker(time.Hour, 2)
+	h.Add("control", control, breaker)
+	h.Check()
+	if !h.Healthy() || h.Status()[0].Breaker != "closed" {
+		t.Fatalf("expected a healthy channel, got %+v", h.Status())
+	}
+
+	server.close()
+	h.Check()
+	if !h.Healthy() || h.Status()[0].ConsecutiveFailures != 1 {
+		t.Fatalf("expected a single failure to be tolerated, got %+v", h.Status())
+	}
+	if err := control.Ping(); err != ErrCircuitOpen {
+		t.Fatalf("expected the breaker to fail fast, got %v", err)
+	}
+	h.Check()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503, got %d", rec.Code)
+	}
+	var statuses []HealthStatus
+	if err := json.NewDecoder(rec.Body).Decode(&statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Healthy || statuses[0].Breaker != "open" || statuses[0].LastError == "" {
+		t.Errorf("unexpected status %+v", statuses)
+	}
+}
+
+func TestHealthChecker_StartStop(t *testing.T) {
+	// stopping a checker never started doesn't block
+	NewHealthChecker(time.Hour, 1).Stop()
+
+	server := newFakeServer(t, 20000, func(string) []string { return []string{"OK"} })
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+
+	h := NewHealthChecker(0, 1)
+	h.Add("search", search, nil)
+	h.Start()
+	h.Start()
+	h.Stop()
+	h.Stop()
+	h.Start()
+	if s := h.Status(); s[0].LastCheck.IsZero() {
+		t.Errorf("expected a check when started, got %+v", s)
+	}
+}
@@ -0,0 +1,54 @@
+package sonic
+
+import (
+	"time"
+)
+
+// CommandEvent describe a command sent to sonic, it is given to the hooks.
+type CommandEvent struct {
+	Channel Channel
+
+	// Command is the name of the command, eg. QUERY or PUSH.
+	Command string
+
+	// Collection and Bucket are empty for commands which don't have them, eg. PING.
+	Collection string
+	Bucket     string
+
+	Start time.Time
+
+	// Duration, BytesSent, BytesReceived and Err are set once the command is done.
+	Duration      time.Duration
+	BytesSent     int
+	BytesReceived int
+	Err           error
+}
+
+// Hook is called before every command of a channel, the function it returns,
+// if not nil, is called once the command is done with the completed event.
+//
+//	func(e sonic.CommandEvent) func(sonic.CommandEvent) {
+//		span := startSpan(e.Command)
+//		return func(e sonic.CommandEvent) {
+//			span.End(e.Err)
+//		}
+//	}
+type Hook func(e CommandEvent) (done func(e CommandEvent))
+
+// WithHooks call hooks around every command of the channel, in the given order.
+// Hooks are called synchronously, they must be fast.
+func WithHooks(hooks ...Hook) Option {
+	return func(d *driver) {
+		d.hooks = append(d.hooks, hooks...)
+	}
+}
+
+func newCommandEvent(cmd Command) CommandEvent {
+	return CommandEvent{
+		Channel:    cmd.Channel,
+		Command:    cmd.Name,
+		Collection: cmd.Collection,
+		Bucket:     cmd.Bucket,
+		Start:      time.Now(),
+	}
+}
@@ -0,0 +1,51 @@
+package sonic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		if strings.HasPrefix(cmd, "FLUSHB") {
+			return []string{"ERR not found"}
+		}
+		return []string{"OK"}
+	})
+
+	var before, after []CommandEvent
+	hook := func(e CommandEvent) func(CommandEvent) {
+		before = append(before, e)
+		return func(e CommandEvent) {
+			after = append(after, e)
+		}
+	}
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword", WithHooks(hook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ing.Push("movies", "general", "id:1", "Spider man", LangNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := ing.FlushBucket("movies", "general"); err == nil {
+		t.Fatal("expected the flush to fail")
+	}
+	_ = ing.Quit()
+
+	if len(before) != 2 || len(after) != 2 {
+		t.Fatalf("expected the hooks to be called for 2 commands, got %d and %d", len(before), len(after))
+	}
+	push := after[0]
+	if push.Channel != Ingest || push.Command != "PUSH" || push.Collection != "movies" || push.Bucket != "general" {
+		t.Errorf("unexpected event %+v", push)
+	}
+	if push.BytesSent != len(`PUSH movies general id:1 "Spider man" LANG(none)`)+2 || push.BytesReceived != len("OK")+2 || push.Err != nil {
+		t.Errorf("unexpected event %+v", push)
+	}
+	if before[0].Duration != 0 || before[0].Err != nil {
+		t.Errorf("expected an incomplete event before the command, got %+v", before[0])
+	}
+	if after[1].Command != "FLUSHB" || after[1].Err == nil {
+		t.Errorf("expected the error in the event, got %+v", after[1])
+	}
+}
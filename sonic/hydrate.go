@@ -0,0 +1,61 @@
+package sonic
+
+// Loader batch-fetch the entities referred by object identifiers, eg. from a database.
+// Objects without entity must be absent from the returned map.
+type Loader[T any] interface {
+	Load(objects []string) (map[string]T, error)
+}
+
+// LoaderFunc is an adapter to use a function as a Loader.
+type LoaderFunc[T any] func(objects []string) (map[string]T, error)
+
+// Load call f(objects).
+func (f LoaderFunc[T]) Load(objects []string) (map[string]T, error) {
+	return f(objects)
+}
+
+// StaleFunc receive the objects returned by sonic whose entities don't exist anymore,
+// so they can be flushed with FlushObject.
+type StaleFunc func(collection, bucket string, objects []string)
+
+// Hydrate load the entities of objects with a single call to loader, preserving the order of objects.
+// Objects without entity are dropped from entities and returned as stale.
+func Hydrate[T any](loader Loader[T], objects []string) (entities []T, stale []string, err error) {
+	if len(objects) == 0 {
+		return []T{}, nil, nil
+	}
+
+	loaded, err := loader.Load(objects)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entities = make([]T, 0, len(objects))
+	for _, object := range objects {
+		entity, ok := loaded[object]
+		if !ok {
+			stale = append(stale, object)
+			continue
+		}
+		entities = append(entities, entity)
+	}
+	return entities, stale, nil
+}
+
+// QueryInto query the search index and load the entities of the results with loader,
+// in sonic ranking order. Objects without entity are dropped, and reported to onStale if it isn't nil.
+func QueryInto[T any](s Searchable, loader Loader[T], onStale StaleFunc, collection, bucket, terms string, limit, offset int, lang Lang) ([]T, error) {
+	objects, err := s.Query(collection, bucket, terms, limit, offset, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, stale, err := Hydrate(loader, objects)
+	if err != nil {
+		return nil, err
+	}
+	if len(stale) > 0 && onStale != nil {
+		onStale(collection, bucket, stale)
+	}
+	return entities, nil
+}
@@ -0,0 +1,42 @@
+package sonic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryInto(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(map[string]string{
+		"movies general": "id:3 id:1 id:2",
+	}))
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+
+	titles := map[string]string{"id:1": "Batman", "id:3": "Spider man"}
+	loader := LoaderFunc[string](func(objects []string) (map[string]string, error) {
+		found := make(map[string]string)
+		for _, o := range objects {
+			if title, ok := titles[o]; ok {
+				found[o] = title
+			}
+		}
+		return found, nil
+	})
+
+	var stale []string
+	results, err := QueryInto[string](search, loader, func(collection, bucket string, objects []string) {
+		stale = objects
+	}, "movies", "general", "man", 10, 0, LangNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Spider man", "Batman"}; !reflect.DeepEqual(results, want) {
+		t.Errorf("got %v, want %v", results, want)
+	}
+	if want := []string{"id:2"}; !reflect.DeepEqual(stale, want) {
+		t.Errorf("got stale %v, want %v", stale, want)
+	}
+}
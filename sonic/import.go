@@ -0,0 +1,351 @@
+package sonic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrImportOptions is throw when the options given to Import are incomplete.
+var ErrImportOptions = errors.New("import needs a collection")
+
+// ImportFormat is the format of the data read by Import.
+type ImportFormat string
+
+const (
+	// ImportJSONLines read a JSON object per line (JSON Lines or NDJSON).
+	ImportJSONLines ImportFormat = "jsonl"
+
+	// ImportCSV read CSV with a header row naming the columns.
+	ImportCSV ImportFormat = "csv"
+)
+
+// ImportOptions configure Import.
+// Fields are the keys of the JSON objects or the columns of the CSV header.
+type ImportOptions struct {
+	Format     ImportFormat
+	Collection string
+
+	// Bucket is the bucket of the records, unless BucketField is set and not empty for a record.
+	Bucket      string
+	BucketField string
+
+	// ObjectField is the field identifying the object, "object" by default.
+	ObjectField string
+
+	// TextFields are the fields holding the text, joined with spaces, "text" by default.
+	// JSON arrays of strings are joined too.
+	TextFields []string
+
+	// Lang is the lang of the records, unless LangField is set and not empty for a record.
+	Lang      Lang
+	LangField string
+
+	// Comma is the CSV delimiter, ',' by default.
+	Comma rune
+
+	// BatchSize is the number of records given to each BulkPush, 1000 by default.
+	BatchSize int
+
+	// ParallelRoutines is given to BulkPush.
+	ParallelRoutines int
+
+	// Offset is the number of records to skip, eg. the ImportSummary.Offset of a failed import to resume it.
+	Offset int
+
+	// Progress, if not nil, is called after each batch.
+	Progress func(s ImportSummary)
+}
+
+// ImportRecordError is a record which can't be imported, because it is malformed or has no object or text.
+type ImportRecordError struct {
+	// Record is the index of the record, starting at 0 after the CSV header.
+	Record int
+	Err    error
+}
+
+// ImportSummary is the result of an import.
+type ImportSummary struct {
+	// Records is the number of records read, including the skipped ones.
+	Records int
+
+	// Skipped is the number of records skipped because of ImportOptions.Offset.
+	Skipped int
+
+	// Pushed is the number of records indexed.
+	Pushed int
+
+	// Invalid are the records which weren't sent to sonic.
+	Invalid []ImportRecordError
+
+	// Failed are the records sonic refused.
+	Failed []IngestBulkError
+
+	// Offset is where to resume the import if it stopped on an error.
+	// The records of the failed batch are pushed again, which doesn't change the index.
+	Offset int
+
+	Duration time.Duration
+}
+
+type importRecord struct {
+	bucket string
+	lang   Lang
+	IngestBulkRecord
+}
+
+// Import read records from r and push them in batches with BulkPush.
+// Records which can't be pushed, eg. refused by sonic or an Interceptor, are reported
+// in the summary and the import goes on. It stops on read errors or when sonic
+// can't be reached, see importStopped; the summary Offset is then where to resume.
+func Import(ingester Ingestable, r io.Reader, opts ImportOptions) (summary ImportSummary, err error) {
+	if opts.ObjectField == "" {
+		opts.ObjectField = "object"
+	}
+	if len(opts.TextFields) == 0 {
+		opts.TextFields = []string{"text"}
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.Collection == "" {
+		return summary, ErrImportOptions
+	}
+
+	var next func() (map[string]string, error)
+	switch opts.Format {
+	case ImportCSV:
+		next, err = csvRecords(r, opts.Comma)
+	case ImportJSONLines, "":
+		next = jsonRecords(r)
+	default:
+		err = fmt.Errorf("unknown import format %q", opts.Format)
+	}
+	if err != nil {
+		return summary, err
+	}
+
+	start := time.Now()
+	defer func() {
+		summary.Duration = time.Since(start)
+	}()
+
+	summary.Offset = opts.Offset
+	batch := make([]importRecord, 0, opts.BatchSize)
+	for {
+		fields, err := next()
+		if err == io.EOF {
+			break
+		}
+		var recordErr *importRecordError
+		if err != nil && !errors.As(err, &recordErr) {
+			return summary, err
+		}
+
+		n := summary.Records
+		summary.Records++
+		if n < opts.Offset {
+			summary.Skipped++
+			continue
+		}
+
+		rec, err := toImportRecord(fields, err, opts)
+		if err != nil {
+			summary.Invalid = append(summary.Invalid, ImportRecordError{n, err})
+			if len(batch) == 0 {
+				summary.Offset = n + 1
+			}
+			continue
+		}
+		batch = append(batch, rec)
+		if len(batch) == opts.BatchSize {
+			if err := importBatch(ingester, batch, summary.Records, &summary, opts); err != nil {
+				return summary, err
+			}
+			batch = batch[:0]
+		}
+	}
+
+	if len(batch) > 0 {
+		return summary, importBatch(ingester, batch, summary.Records, &summary, opts)
+	}
+	return summary, nil
+}
+
+// importBatch push the records of a batch, grouped by bucket and lang.
+// end is the index of the record after the batch.
+func importBatch(ingester Ingestable, batch []importRecord, end int, summary *ImportSummary, opts ImportOptions) error {
+	type group struct {
+		bucket string
+		lang   Lang
+	}
+	var order []group
+	groups := make(map[group][]IngestBulkRecord)
+	for _, rec := range batch {
+		g := group{rec.bucket, rec.lang}
+		if _, ok := groups[g]; !ok {
+			order = append(order, g)
+		}
+		groups[g] = append(groups[g], rec.IngestBulkRecord)
+	}
+
+	var failed []IngestBulkError
+	for _, g := range order {
+		errs := ingester.BulkPush(opts.Collection, g.bucket, opts.ParallelRoutines, groups[g], g.lang)
+		for _, e := range errs {
+			if importStopped(e.Error) {
+				return fmt.Errorf("import stopped, resume at offset %d: %w", summary.Offset, e.Error)
+			}
+		}
+		failed = append(failed, errs...)
+	}
+
+	summary.Pushed += len(batch) - len(failed)
+	summary.Failed = append(summary.Failed, failed...)
+	summary.Offset = end
+	if opts.Progress != nil {
+		opts.Progress(*summary)
+	}
+	return nil
+}
+
+// importStopped report whether err means sonic can't be reached, so the next records would fail too.
+func importStopped(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, ErrClosed) || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrAuthFailed) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+func toImportRecord(fields map[string]string, err error, opts ImportOptions) (importRecord, error) {
+	if err != nil {
+		return importRecord{}, err
+	}
+
+	rec := importRecord{bucket: opts.Bucket, lang: opts.Lang}
+	if opts.BucketField != "" && fields[opts.BucketField] != "" {
+		rec.bucket = fields[opts.BucketField]
+	}
+	if opts.LangField != "" && fields[opts.LangField] != "" {
+		rec.lang = Lang(fields[opts.LangField])
+	}
+	rec.Object = fields[opts.ObjectField]
+
+	texts := make([]string, 0, len(opts.TextFields))
+	for _, f := range opts.TextFields {
+		if t := strings.TrimSpace(fields[f]); t != "" {
+			texts = append(texts, t)
+		}
+	}
+	rec.Text = strings.Join(texts, " ")
+
+	switch {
+	case rec.bucket == "":
+		return rec, errors.New("missing bucket")
+	case rec.Object == "" || strings.ContainsAny(rec.Object, " \t\r\n"):
+		return rec, fmt.Errorf("missing or invalid object %q", rec.Object)
+	case rec.Text == "":
+		return rec, errors.New("missing text")
+	}
+	return rec, nil
+}
+
+// importRecordError is a malformed record, the following ones can still be read.
+type importRecordError struct {
+	err error
+}
+
+func (e *importRecordError) Error() string {
+	return e.err.Error()
+}
+
+func (e *importRecordError) Unwrap() error {
+	return e.err
+}
+
+func jsonRecords(r io.Reader) func() (map[string]string, error) {
+	reader := bufio.NewReader(r)
+	return func() (map[string]string, error) {
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil && (err != io.EOF || len(line) == 0) {
+				return nil, err
+			}
+			line = bytes.TrimSpace(line)
+			if len(line) == 0 {
+				continue
+			}
+
+			var object map[string]interface{}
+			d := json.NewDecoder(bytes.NewReader(line))
+			d.UseNumber()
+			if err := d.Decode(&object); err != nil {
+				return nil, &importRecordError{err}
+			}
+			fields := make(map[string]string, len(object))
+			for k, v := range object {
+				fields[k] = jsonString(v)
+			}
+			return fields, nil
+		}
+	}
+}
+
+// jsonString return the text of a JSON value, arrays are joined with spaces.
+func jsonString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, e := range v {
+			if s := jsonString(e); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, " ")
+	case map[string]interface{}:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func csvRecords(r io.Reader, comma rune) (func() (map[string]string, error), error) {
+	reader := csv.NewReader(r)
+	if comma != 0 {
+		reader.Comma = comma
+	}
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	return func() (map[string]string, error) {
+		row, err := reader.Read()
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return nil, &importRecordError{err}
+			}
+			return nil, err
+		}
+		if len(row) != len(header) {
+			return nil, &importRecordError{fmt.Errorf("record has %d fields, header has %d", len(row), len(header))}
+		}
+		fields := make(map[string]string, len(header))
+		for n, name := range header {
+			fields[name] = row[n]
+		}
+		return fields, nil
+	}, nil
+}
@@ -0,0 +1,134 @@
+package sonic
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		if strings.Contains(cmd, "id:refused") {
+			return []string{"ERR refused"}
+		}
+		return []string{"OK"}
+	})
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	data := `{"id": "id:1", "title": "Spider man", "tags": ["action", "hero"]}
+{"id": "id:2", "title": "Amélie", "bucket": "french", "lang": "fra"}
+not json
+
+{"id": "id:3"}
+{"id": "id:refused", "title": "Batman"}
+{"id": 4, "title": "Star wars"}`
+
+	var progress []int
+	summary, err := Import(ing, strings.NewReader(data), ImportOptions{
+		Collection:  "movies",
+		Bucket:      "general",
+		BucketField: "bucket",
+		ObjectField: "id",
+		TextFields:  []string{"title", "tags"},
+		LangField:   "lang",
+		Lang:        LangNone,
+		BatchSize:   2,
+		Progress:    func(s ImportSummary) { progress = append(progress, s.Offset) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Records != 6 || summary.Pushed != 3 || summary.Offset != 6 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if len(summary.Invalid) != 2 || summary.Invalid[0].Record != 2 || summary.Invalid[1].Record != 3 {
+		t.Errorf("expected records 2 and 3 to be invalid, got %+v", summary.Invalid)
+	}
+	if len(summary.Failed) != 1 || summary.Failed[0].Object != "id:refused" {
+		t.Errorf("expected id:refused to fail, got %+v", summary.Failed)
+	}
+	if !reflect.DeepEqual(progress, []int{2, 6}) {
+		t.Errorf("unexpected progress %v", progress)
+	}
+
+	received := server.received()
+	sort.Strings(received)
+	expected := []string{
+		`PUSH movies french id:2 "Amélie" LANG(fra)`,
+		`PUSH movies general 4 "Star wars" LANG(none)`,
+		`PUSH movies general id:1 "Spider man action hero" LANG(none)`,
+		`PUSH movies general id:refused "Batman" LANG(none)`,
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+}
+
+func TestImport_CSVResume(t *testing.T) {
+	server := newFakeServer(t, 20000, func(string) []string { return []string{"OK"} })
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	data := "object;text\nid:1;Spider man\nid:2;\"Batman; the dark knight\"\nid:3;Star wars\n"
+	opts := ImportOptions{Format: ImportCSV, Collection: "movies", Bucket: "general", Comma: ';', Offset: 1}
+	summary, err := Import(ing, strings.NewReader(data), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Records != 3 || summary.Skipped != 1 || summary.Pushed != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	expected := []string{`PUSH movies general id:2 "Batman; the dark knight"`, `PUSH movies general id:3 "Star wars"`}
+	if received := server.received(); !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+
+	// the import stops when sonic is down, the offset is where to resume
+	server.close()
+	opts.Offset = 0
+	opts.BatchSize = 2
+	summary, err = Import(ing, strings.NewReader(data), opts)
+	if err == nil || summary.Offset != 0 || summary.Pushed != 0 {
+		t.Errorf("expected the import to stop at offset 0, got %+v %v", summary, err)
+	}
+
+	if _, err := Import(ing, strings.NewReader(data), ImportOptions{}); !errors.Is(err, ErrImportOptions) {
+		t.Errorf("expected an options error, got %v", err)
+	}
+}
+
+func TestImport_InterceptorRefusal(t *testing.T) {
+	server := newFakeServer(t, 20000, func(string) []string { return []string{"OK"} })
+	errRefused := errors.New("bucket is read only")
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword", WithInterceptors(func(cmd Command, next Invoker) (string, error) {
+		if cmd.Bucket == "archive" {
+			return "", errRefused
+		}
+		return next(cmd)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	// the refused records are failed, the import isn't stopped as if sonic was down
+	data := `{"object": "id:1", "text": "Spider man", "bucket": "archive"}
+{"object": "id:2", "text": "Batman"}`
+	summary, err := Import(ing, strings.NewReader(data), ImportOptions{Collection: "movies", Bucket: "general", BucketField: "bucket", BatchSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Pushed != 1 || summary.Offset != 2 || len(summary.Failed) != 1 || summary.Failed[0].Error != errRefused {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
@@ -0,0 +1,393 @@
+package sonic
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrIndexerType is throw when a value can't be indexed by an Indexer.
+var ErrIndexerType = errors.New("value must be a struct or a pointer to a struct")
+
+// Indexer index Go structs described with sonic struct tags.
+//
+// The collection, bucket and object identifier are set on a blank field,
+// the bucket defaults to "default". Text fields are pushed in the order of
+// declaration, fields with the same lang are pushed together:
+//
+//	type Product struct {
+//		_           struct{} `sonic:"collection=products,bucket=default,object=ID"`
+//		ID          int
+//		Name        string   `sonic:"text"`
+//		Tags        []string `sonic:"text"`
+//		Description string   `sonic:"text,lang=fra"`
+//	}
+//
+// Object and text fields can be strings, numbers or fmt.Stringer,
+// text fields can also be slices of those.
+type Indexer struct {
+	// ParallelRoutines is the number of goroutines used by bulk operations, see BulkPush.
+	ParallelRoutines int
+
+	ingester Ingestable
+
+	mu    sync.Mutex
+	types map[reflect.Type]*indexedType
+}
+
+// NewIndexer create an Indexer using the given ingester.
+func NewIndexer(ingester Ingestable) *Indexer {
+	return &Indexer{
+		ParallelRoutines: runtime.NumCPU(),
+		ingester:         ingester,
+		types:            make(map[reflect.Type]*indexedType),
+	}
+}
+
+// indexedType is the parsed description of a struct type.
+type indexedType struct {
+	collection string
+	bucket     string
+	object     int
+	texts      []textField
+}
+
+type textField struct {
+	index int
+	lang  Lang
+}
+
+// indexedValue is what is pushed for a struct value.
+type indexedValue struct {
+	collection, bucket, object string
+
+	// groups contains the text of the value for each lang, in order of first appearance
+	groups []textGroup
+}
+
+type textGroup struct {
+	lang Lang
+	text string
+}
+
+// Index push the text fields of v.
+func (i *Indexer) Index(v interface{}) error {
+	value, err := i.value(v)
+	if err != nil {
+		return err
+	}
+	for _, g := range value.groups {
+		err = i.ingester.Push(value.collection, value.bucket, value.object, g.text, g.lang)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete flush the indexed data of v.
+func (i *Indexer) Delete(v interface{}) error {
+	value, err := i.value(v)
+	if err != nil {
+		return err
+	}
+	return i.ingester.FlushObject(value.collection, value.bucket, value.object)
+}
+
+// Reindex replace the indexed data of v by its current text fields.
+func (i *Indexer) Reindex(v interface{}) error {
+	value, err := i.value(v)
+	if err != nil {
+		return err
+	}
+	if len(value.groups) == 0 {
+		return i.ingester.FlushObject(value.collection, value.bucket, value.object)
+	}
+
+	err = i.ingester.Replace(value.collection, value.bucket, value.object, value.groups[0].text, value.groups[0].lang)
+	if err != nil {
+		return err
+	}
+	for _, g := range value.groups[1:] {
+		err = i.ingester.Push(value.collection, value.bucket, value.object, g.text, g.lang)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IndexAll push the text fields of every element of the slice values with BulkPush.
+// An error is returned if values or one of its elements can't be indexed, in which case nothing is pushed.
+func (i *Indexer) IndexAll(values interface{}) ([]IngestBulkError, error) {
+	all, err := i.values(values)
+	if err != nil {
+		return nil, err
+	}
+	return i.bulk(all, 0, i.ingester.BulkPush, nil), nil
+}
+
+// DeleteAll flush the indexed data of every element of the slice values.
+func (i *Indexer) DeleteAll(values interface{}) ([]IngestBulkError, error) {
+	all, err := i.values(values)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]IngestBulkError, 0)
+	for _, value := range all {
+		err := i.ingester.FlushObject(value.collection, value.bucket, value.object)
+		if err != nil {
+			errs = append(errs, IngestBulkError{value.object, err})
+		}
+	}
+	return errs, nil
+}
+
+// ReindexAll replace the indexed data of every element of the slice values with BulkReplace.
+// Objects with texts in several langs are replaced with their first lang, then the others are pushed.
+func (i *Indexer) ReindexAll(values interface{}) ([]IngestBulkError, error) {
+	all, err := i.values(values)
+	if err != nil {
+		return nil, err
+	}
+
+	// values without text only need to be flushed
+	errs := make([]IngestBulkError, 0)
+	withText := make([]indexedValue, 0, len(all))
+	for _, value := range all {
+		if len(value.groups) > 0 {
+			withText = append(withText, value)
+			continue
+		}
+		err := i.ingester.FlushObject(value.collection, value.bucket, value.object)
+		if err != nil {
+			errs = append(errs, IngestBulkError{value.object, err})
+		}
+	}
+
+	// don't push the other langs of the objects which couldn't be replaced
+	failed := make(map[indexedObject]bool)
+	errs = append(errs, i.bulk(withText, 1, i.ingester.BulkReplace, failed)...)
+	rest := make([]indexedValue, 0, len(withText))
+	for _, value := range withText {
+		if !failed[indexedObject{value.collection, value.bucket, value.object}] && len(value.groups) > 1 {
+			value.groups = value.groups[1:]
+			rest = append(rest, value)
+		}
+	}
+	return append(errs, i.bulk(rest, 0, i.ingester.BulkPush, nil)...), nil
+}
+
+// indexedObject identify an object, the same identifier can be used in several collections or buckets.
+type indexedObject struct {
+	collection, bucket, object string
+}
+
+type bulkFunc func(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError
+
+// bulk group the text groups of the values by collection, bucket and lang and call fn for each of them.
+// If limit > 0, only the first limit groups of each value are taken.
+// The objects which failed are added to failed if it isn't nil.
+func (i *Indexer) bulk(values []indexedValue, limit int, fn bulkFunc, failed map[indexedObject]bool) []IngestBulkError {
+	type key struct {
+		collection, bucket string
+		lang               Lang
+	}
+	var keys []key
+	records := make(map[key][]IngestBulkRecord)
+	for _, value := range values {
+		groups := value.groups
+		if limit > 0 && len(groups) > limit {
+			groups = groups[:limit]
+		}
+		for _, g := range groups {
+			k := key{value.collection, value.bucket, g.lang}
+			if _, ok := records[k]; !ok {
+				keys = append(keys, k)
+			}
+			records[k] = append(records[k], IngestBulkRecord{value.object, g.text})
+		}
+	}
+
+	errs := make([]IngestBulkError, 0)
+	for _, k := range keys {
+		bulkErrs := fn(k.collection, k.bucket, i.ParallelRoutines, records[k], k.lang)
+		for _, e := range bulkErrs {
+			if failed != nil {
+				failed[indexedObject{k.collection, k.bucket, e.Object}] = true
+			}
+		}
+		errs = append(errs, bulkErrs...)
+	}
+	return errs
+}
+
+// values extract the indexed values of every element of a slice.
+func (i *Indexer) values(values interface{}) ([]indexedValue, error) {
+	rv := reflect.ValueOf(values)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("indexer expects a slice, got %T", values)
+	}
+
+	all := make([]indexedValue, 0, rv.Len())
+	for n := 0; n < rv.Len(); n++ {
+		value, err := i.value(rv.Index(n).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", n, err)
+		}
+		all = append(all, value)
+	}
+	return all, nil
+}
+
+// value extract the collection, bucket, object and texts of a struct value.
+func (i *Indexer) value(v interface{}) (indexedValue, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return indexedValue{}, ErrIndexerType
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return indexedValue{}, ErrIndexerType
+	}
+
+	t, err := i.indexedType(rv.Type())
+	if err != nil {
+		return indexedValue{}, err
+	}
+
+	value := indexedValue{
+		collection: t.collection,
+		bucket:     t.bucket,
+		object:     fieldText(rv.Field(t.object)),
+	}
+	if value.object == "" {
+		return indexedValue{}, fmt.Errorf("empty object identifier for %s", rv.Type())
+	}
+
+	for _, f := range t.texts {
+		text := fieldText(rv.Field(f.index))
+		if text == "" {
+			continue
+		}
+		found := false
+		for n := range value.groups {
+			if value.groups[n].lang == f.lang {
+				value.groups[n].text += " " + text
+				found = true
+				break
+			}
+		}
+		if !found {
+			value.groups = append(value.groups, textGroup{f.lang, text})
+		}
+	}
+	return value, nil
+}
+
+// indexedType parse the sonic tags of a struct type, results are cached.
+func (i *Indexer) indexedType(rt reflect.Type) (*indexedType, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if t, ok := i.types[rt]; ok {
+		return t, nil
+	}
+
+	t := &indexedType{bucket: "default", object: -1}
+	objectField := ""
+	for n := 0; n < rt.NumField(); n++ {
+		f := rt.Field(n)
+		tag, ok := f.Tag.Lookup("sonic")
+		if !ok {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		if parts[0] == "text" {
+			field := textField{index: n}
+			for _, p := range parts[1:] {
+				if !strings.HasPrefix(p, "lang=") {
+					return nil, fmt.Errorf("invalid tag option %q on field %s.%s", p, rt, f.Name)
+				}
+				field.lang = Lang(strings.TrimPrefix(p, "lang="))
+			}
+			t.texts = append(t.texts, field)
+			continue
+		}
+
+		for _, p := range parts {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("invalid tag option %q on field %s.%s", p, rt, f.Name)
+			}
+			switch kv[0] {
+			case "collection":
+				t.collection = kv[1]
+			case "bucket":
+				t.bucket = kv[1]
+			case "object":
+				objectField = kv[1]
+			default:
+				return nil, fmt.Errorf("invalid tag option %q on field %s.%s", p, rt, f.Name)
+			}
+		}
+	}
+
+	if t.collection == "" || objectField == "" {
+		return nil, fmt.Errorf("%s has no collection or object in its sonic tag", rt)
+	}
+	f, ok := rt.FieldByName(objectField)
+	if !ok || len(f.Index) != 1 {
+		return nil, fmt.Errorf("object field %s not found in %s", objectField, rt)
+	}
+	t.object = f.Index[0]
+
+	i.types[rt] = t
+	return t, nil
+}
+
+// fieldText format a field value as text, slices are joined with spaces.
+func fieldText(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		parts := make([]string, 0, v.Len())
+		for n := 0; n < v.Len(); n++ {
+			if s := fieldText(v.Index(n)); s != "" {
+				parts = append(parts, s)
+			}
+		}
+		return strings.Join(parts, " ")
+	}
+	if v.CanInterface() {
+		return fmt.Sprint(v.Interface())
+	}
+	return ""
+}
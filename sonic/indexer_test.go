@@ -0,0 +1,147 @@
+package sonic
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type indexedProduct struct {
+	_           struct{} `sonic:"collection=products,object=ID"`
+	ID          int
+	Name        string   `sonic:"text"`
+	Tags        []string `sonic:"text"`
+	Description string   `sonic:"text,lang=fra"`
+	Price       float64
+}
+
+func TestIndexer_Value(t *testing.T) {
+	i := NewIndexer(nil)
+
+	value, err := i.value(&indexedProduct{
+		ID:          42,
+		Name:        "Chair",
+		Tags:        []string{"wood", "furniture"},
+		Description: "Une chaise",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := indexedValue{
+		collection: "products",
+		bucket:     "default",
+		object:     "42",
+		groups: []textGroup{
+			{LangAutoDetect, "Chair wood furniture"},
+			{LangFra, "Une chaise"},
+		},
+	}
+	if !reflect.DeepEqual(value, want) {
+		t.Errorf("got %+v, want %+v", value, want)
+	}
+
+	if _, err := i.value("not a struct"); err != ErrIndexerType {
+		t.Errorf("expected ErrIndexerType, got %v", err)
+	}
+	type untagged struct{ ID string }
+	if _, err := i.value(untagged{"1"}); err == nil {
+		t.Error("expected an error for a struct without sonic tag")
+	}
+	if _, err := i.values([]indexedProduct{{ID: 1}, {ID: 2}}); err != nil {
+		t.Error(err)
+	}
+}
+
+type indexedReview struct {
+	_       struct{} `sonic:"collection=reviews,bucket=public,object=ID"`
+	ID      string
+	Title   string `sonic:"text,lang=eng"`
+	Comment string `sonic:"text,lang=fra"`
+}
+
+func TestIndexer_IndexAndReindex(t *testing.T) {
+	server := newFakeServer(t, 20000, func(string) []string { return []string{"OK"} })
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	i := NewIndexer(ing)
+	product := indexedProduct{ID: 42, Name: "Chair", Description: "Une chaise"}
+	if err := i.Index(product); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Reindex(&product); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Reindex(indexedProduct{ID: 43}); err != nil {
+		t.Fatal(err)
+	}
+	if err := i.Index(42); err != ErrIndexerType {
+		t.Errorf("expected ErrIndexerType, got %v", err)
+	}
+
+	expected := []string{
+		`PUSH products default 42 "Chair"`,
+		`PUSH products default 42 "Une chaise" LANG(fra)`,
+		`FLUSHO products default 42`,
+		`PUSH products default 42 "Chair"`,
+		`PUSH products default 42 "Une chaise" LANG(fra)`,
+		`FLUSHO products default 43`,
+	}
+	if received := server.received(); !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+}
+
+func TestIndexer_Bulk(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		if strings.HasPrefix(cmd, "FLUSHO products default 1") || strings.Contains(cmd, "Broken") {
+			return []string{"ERR refused"}
+		}
+		return []string{"OK"}
+	})
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	i := NewIndexer(ing)
+	i.ParallelRoutines = 1
+
+	errs, err := i.IndexAll([]indexedProduct{{ID: 1, Name: "Chair"}, {ID: 2, Name: "Broken table"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].Object != "2" {
+		t.Errorf("expected object 2 to fail, got %v", errs)
+	}
+	if _, err := i.IndexAll([]interface{}{indexedProduct{ID: 1}, "not a struct"}); !errors.Is(err, ErrIndexerType) {
+		t.Errorf("expected ErrIndexerType for an invalid element, got %v", err)
+	}
+
+	// the product 1 can't be replaced, the review 1 is a distinct object which can
+	before := len(server.received())
+	errs, err = i.ReindexAll([]interface{}{
+		indexedProduct{ID: 1, Name: "Chair", Description: "Une chaise"},
+		indexedReview{ID: "1", Title: "Great", Comment: "Super"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 1 || errs[0].Object != "1" {
+		t.Errorf("expected the product 1 to fail, got %v", errs)
+	}
+	expected := []string{
+		`FLUSHO products default 1`,
+		`FLUSHO reviews public 1`,
+		`PUSH reviews public 1 "Great" LANG(eng)`,
+		`PUSH reviews public 1 "Super" LANG(fra)`,
+	}
+	if received := server.received()[before:]; !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+}
@@ -1,12 +1,54 @@
 package sonic
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
-	"unicode/utf8"
 )
 
+// ErrCommandTooLong is throw when the collection, bucket and object names
+// leave no room for the text in the sonic buffer.
+var ErrCommandTooLong = errors.New("command doesn't fit in sonic buffer")
+
+// PushError is returned by Push when the text was split in several commands
+// and one of them failed. Chunks before the failed one have been indexed,
+// unless they were rolled back (see WithPushRollback).
+type PushError struct {
+	Object string
+
+	// Chunks is the number of commands the text was split in.
+	Chunks int
+
+	// Pushed is the number of chunks indexed before the failure,
+	// ie. chunks [0, Pushed) succeeded and chunk Pushed failed.
+	Pushed int
+
+	// RolledBack is true when the pushed chunks have been popped.
+	RolledBack bool
+
+	// RollbackErr is the error of the rollback, if any.
+	RollbackErr error
+
+	// Err is the error of the failed chunk.
+	Err error
+}
+
+func (e *PushError) Error() string {
+	msg := fmt.Sprintf("push of object %s failed at chunk %d/%d: %v", e.Object, e.Pushed+1, e.Chunks, e.Err)
+	if e.RolledBack {
+		msg += " (rolled back)"
+	} else if e.RollbackErr != nil {
+		msg += fmt.Sprintf(" (rollback failed: %v)", e.RollbackErr)
+	}
+	return msg
+}
+
+// Unwrap return the error of the failed chunk.
+func (e *PushError) Unwrap() error {
+	return e.Err
+}
+
 // IngestBulkRecord is the struct to be used as a list in bulk operation.
 type IngestBulkRecord struct {
 	Object, Text string
@@ -57,12 +99,34 @@ type Ingestable interface {
 	// Command syntax FLUSHO <collection> <bucket> <object>.
 	FlushObject(collection, bucket, object string) (err error)
 
+	// Replace flush all indexed data from an object then push the new text.
+	// Replacements of the same object done through this client are serialized,
+	// so concurrent updates can't interleave their FLUSHO and PUSH commands.
+	// If the push fails after the flush succeeded, the object is left unindexed
+	// and the error is returned: Replace can be retried safely.
+	// Command syntax FLUSHO <collection> <bucket> <object> then PUSH <collection> <bucket> <object> "<text>" [LANG(<locale>)]?
+	Replace(collection, bucket, object, text string, lang Lang) (err error)
+
+	// BulkReplace will execute N (parallelRoutines) goroutines at the same time to
+	// dispatch the records at best, see Replace.
+	// If parallelRoutines <= 0; parallelRoutines will be equal to 1.
+	// If parallelRoutines > len(records); parallelRoutines will be equal to len(records).
+	BulkReplace(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError
+
+	// Update an indexed object from its previous text without re-pushing everything.
+	// Both texts are tokenized client side: terms that disappeared are popped and
+	// only the terms that weren't in oldText are pushed.
+	// Updates of the same object are serialized like Replace.
+	// Command syntax PUSH <collection> <bucket> <object> "<text>" [LANG(<locale>)]? then POP <collection> <bucket> <object> "<text>".
+	Update(collection, bucket, object, oldText, newText string, lang Lang) (err error)
+
 	// Quit refer to the Base interface
 	Quit() (err error)
 
 	// Ping refer to the Base interface
 	Ping() (err error)
 }
+
 type ingesterCommands string
 
 const (
@@ -76,58 +140,96 @@ const (
 
 type ingesterChannel struct {
 	*driver
+	locks *objectLocks
 }
 
 // NewIngester create a new driver instance with a ingesterChannel instance.
 // Only way to get a Ingestable implementation.
-func NewIngester(host string, port int, password string) (Ingestable, error) {
-	driver := &driver{
-		Host:     host,
-		Port:     port,
-		Password: password,
-		channel:  Ingest,
-	}
+func NewIngester(host string, port int, password string, opts ...Option) (Ingestable, error) {
+	driver := newDriver(host, port, password, Ingest, opts)
 	err := driver.Connect()
 	if err != nil {
 		return nil, err
 	}
 	return ingesterChannel{
 		driver: driver,
+		locks:  newObjectLocks(),
 	}, nil
 }
 
-func (i ingesterChannel) Push(collection, bucket, object, text string, lang Lang) (err error) {
-	//
-	patterns := []struct {
-		Pattern     string
-		Replacement string
-	}{{"\\", "\\\\"},
-		{"\n", "\\n"},
-		{"\"", "\\\""}}
-	for _, v := range patterns {
-		text = strings.Replace(text, v.Pattern, v.Replacement, -1)
+// fork open a new connection with the same settings, sharing the object locks.
+// It is used by bulk operations to dispatch records on several connections.
+func (i ingesterChannel) fork() (ingesterChannel, error) {
+	driver := i.driver.clone()
+	err := driver.Connect()
+	if err != nil {
+		return ingesterChannel{}, err
 	}
+	return ingesterChannel{
+		driver: driver,
+		locks:  i.locks,
+	}, nil
+}
 
-	chunks := splitText(text, i.cmdMaxBytes/2)
-	// split chunks with partial success will yield single error
-	for _, chunk := range chunks {
-		ff := fmt.Sprintf("%s %s %s %s \"%s\""+langFormat(lang), push, collection, bucket, object, chunk, lang)
-		err = i.write(ff)
+func (i ingesterChannel) Push(collection, bucket, object, text string, lang Lang) (err error) {
+	return i.sendText(push, collection, bucket, object, text, lang)
+}
 
-		if err != nil {
+// sendText escape the text and send it with the given command, splitting it
+// in as many commands as needed to fit the sonic buffer.
+// When a push is split in several chunks, failures are reported with a *PushError.
+func (i ingesterChannel) sendText(cmd ingesterCommands, collection, bucket, object, text string, lang Lang) (err error) {
+	text = escapeText(text)
+	format := "%s %s %s %s \"%s\"" + langFormat(lang)
+
+	maxLen := 0
+	if bufferSize := i.bufferSize(); bufferSize > 0 {
+		// the chunk must fit in the buffer along with the rest of the command and the CRLF
+		overhead := len(fmt.Sprintf(format, cmd, collection, bucket, object, "", lang)) + 2
+		maxLen = bufferSize - overhead
+		if maxLen <= 0 {
+			return ErrCommandTooLong
+		}
+	}
+
+	chunks := splitText(text, maxLen)
+	for n, chunk := range chunks {
+		err = i.sendChunk(fmt.Sprintf(format, cmd, collection, bucket, object, chunk, lang))
+		if err == nil {
+			continue
+		}
+		if cmd != push || len(chunks) == 1 {
 			return err
 		}
 
-		// sonic should sent OK
-		_, err = i.read()
+		pushErr := &PushError{Object: object, Chunks: len(chunks), Pushed: n, Err: err}
+		if i.pushRollback && n > 0 {
+			pushErr.RollbackErr = i.rollback(collection, bucket, object, chunks[:n])
+			pushErr.RolledBack = pushErr.RollbackErr == nil
+		}
+		return pushErr
+	}
+
+	return nil
+}
+
+// rollback pop already escaped chunks.
+func (i ingesterChannel) rollback(collection, bucket, object string, chunks []string) error {
+	for _, chunk := range chunks {
+		err := i.sendChunk(fmt.Sprintf("%s %s %s %s \"%s\"", pop, collection, bucket, object, chunk))
 		if err != nil {
 			return err
 		}
 	}
-
 	return nil
 }
 
+func (i ingesterChannel) sendChunk(cmd string) error {
+	// sonic should sent OK
+	_, err := i.exec(cmd, 1)
+	return err
+}
+
 func langFormat(lang Lang) string {
 	if lang != "" {
 		return " LANG(%s)"
@@ -135,85 +237,102 @@ func langFormat(lang Lang) string {
 	return "%s"
 }
 
-// Ensure splitting on a valid leading byte
-// Slicing the string directly is more efficient than converting to []byte and back because
-// since a string is immutable and a []byte isn't,
-// the data must be copied to new memory upon conversion,
-// taking O(n) time (both ways!),
-// whereas slicing a string simply returns a new string header backed by the same array as the original
-// (taking constant time).
-func splitText(longString string, maxLen int) []string {
-	var splits []string
-
-	var l, r int
-	for l, r = 0, maxLen; r < len(longString); l, r = r, r+maxLen {
-		for !utf8.RuneStart(longString[r]) {
-			r--
-		}
-		splits = append(splits, longString[l:r])
-	}
-	splits = append(splits, longString[l:])
-	return splits
-}
-
 func (i ingesterChannel) BulkPush(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) (errs []IngestBulkError) {
-	if parallelRoutines <= 0 {
-		parallelRoutines = 1
-	}
-
-	// chunk array into N (parallelRoutines) parts
-	divided := divideIngestBulkRecords(records, parallelRoutines)
-
-	bulkErrorChan := make(chan []IngestBulkError)
-	defer close(bulkErrorChan)
+	return i.dispatchBulk(parallelRoutines, records, func(ing ingesterChannel, rec IngestBulkRecord) error {
+		return ing.Push(collection, bucket, rec.Object, rec.Text, lang)
+	})
+}
 
-	for _, r := range divided {
-		r := r
-		go func(driver *driver, collection, bucket string, recs []IngestBulkRecord, bulkErrorChan chan<- []IngestBulkError) {
-			errs := make([]IngestBulkError, 0)
-			newIngester, _ := NewIngester(driver.Host, driver.Port, driver.Password)
+func (i ingesterChannel) Pop(collection, bucket, object, text string) (err error) {
+	return i.sendText(pop, collection, bucket, object, text, LangAutoDetect)
+}
 
-			for _, rec := range recs {
-				if newIngester == nil {
-					addBulkError(&errs, rec, ErrClosed)
-					continue
-				}
-				err := newIngester.Push(collection, bucket, rec.Object, rec.Text, lang)
-				if err != nil {
-					addBulkError(&errs, rec, err)
-				}
-			}
+func (i ingesterChannel) BulkPop(collection, bucket string, parallelRoutines int, records []IngestBulkRecord) (errs []IngestBulkError) {
+	return i.dispatchBulk(parallelRoutines, records, func(ing ingesterChannel, rec IngestBulkRecord) error {
+		return ing.Pop(collection, bucket, rec.Object, rec.Text)
+	})
+}
 
-			if newIngester != nil {
-				_ = newIngester.Quit()
-			}
-			bulkErrorChan <- errs
-		}(i.driver, collection, bucket, r, bulkErrorChan)
+func (i ingesterChannel) Count(collection, bucket, object string) (cnt int, err error) {
+	// RESULT NUMBER
+	r, err := i.exec(fmt.Sprintf("%s %s %s", count, collection, buildCountQuery(bucket, object)), 1)
+	if err != nil {
+		return 0, err
 	}
+	return strconv.Atoi(r[7:])
+}
 
-	errs = make([]IngestBulkError, 0)
-	for range divided {
-		errs = append(errs, <-bulkErrorChan...)
+func buildCountQuery(bucket, object string) string {
+	builder := strings.Builder{}
+	if bucket != "" {
+		builder.WriteString(bucket)
+		if object != "" {
+			builder.WriteString(" " + object)
+		}
 	}
+	return builder.String()
+}
 
-	return errs
+func (i ingesterChannel) FlushCollection(collection string) (err error) {
+	// sonic should sent OK
+	_, err = i.exec(fmt.Sprintf("%s %s", flushc, collection), 1)
+	return err
 }
 
-func (i ingesterChannel) Pop(collection, bucket, object, text string) (err error) {
-	err = i.write(fmt.Sprintf("%s %s %s %s \"%s\"", pop, collection, bucket, object, text))
-	if err != nil {
-		return err
-	}
+func (i ingesterChannel) FlushBucket(collection, bucket string) (err error) {
+	// sonic should sent OK
+	_, err = i.exec(fmt.Sprintf("%s %s %s", flushb, collection, bucket), 1)
+	return err
+}
 
+func (i ingesterChannel) FlushObject(collection, bucket, object string) (err error) {
 	// sonic should sent OK
-	_, err = i.read()
+	_, err = i.exec(fmt.Sprintf("%s %s %s %s", flusho, collection, bucket, object), 1)
+	return err
+}
+
+func (i ingesterChannel) Replace(collection, bucket, object, text string, lang Lang) (err error) {
+	unlock := i.locks.lock(collection, bucket, object)
+	defer unlock()
+
+	err = i.FlushObject(collection, bucket, object)
 	if err != nil {
 		return err
 	}
+	return i.Push(collection, bucket, object, text, lang)
+}
+
+func (i ingesterChannel) BulkReplace(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) (errs []IngestBulkError) {
+	return i.dispatchBulk(parallelRoutines, records, func(ing ingesterChannel, rec IngestBulkRecord) error {
+		return ing.Replace(collection, bucket, rec.Object, rec.Text, lang)
+	})
+}
+
+func (i ingesterChannel) Update(collection, bucket, object, oldText, newText string, lang Lang) (err error) {
+	added, removed := diffTerms(oldText, newText)
+
+	unlock := i.locks.lock(collection, bucket, object)
+	defer unlock()
+
+	// push first so the object stays searchable during the update
+	if len(added) > 0 {
+		err = i.Push(collection, bucket, object, joinTerms(added), lang)
+		if err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		err = i.Pop(collection, bucket, object, joinTerms(removed))
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (i ingesterChannel) BulkPop(collection, bucket string, parallelRoutines int, records []IngestBulkRecord) (errs []IngestBulkError) {
+// dispatchBulk split the records into N (parallelRoutines) parts, each part is
+// handled by a goroutine owning its own connection and calling fn for every record.
+func (i ingesterChannel) dispatchBulk(parallelRoutines int, records []IngestBulkRecord, fn func(ing ingesterChannel, rec IngestBulkRecord) error) (errs []IngestBulkError) {
 	if parallelRoutines <= 0 {
 		parallelRoutines = 1
 	}
@@ -225,30 +344,26 @@ func (i ingesterChannel) BulkPop(collection, bucket string, parallelRoutines int
 	defer close(bulkErrorChan)
 
 	for _, r := range divided {
-		r := r
-		go func(driver *driver, collection, bucket string, recs []IngestBulkRecord, bulkErrorChan chan<- []IngestBulkError) {
+		go func(recs []IngestBulkRecord, bulkErrorChan chan<- []IngestBulkError) {
 			errs := make([]IngestBulkError, 0)
-			newIngester, _ := NewIngester(driver.Host, driver.Port, driver.Password)
+			newIngester, err := i.fork()
 
 			for _, rec := range recs {
-				if newIngester == nil {
-					addBulkError(&errs, rec, ErrClosed)
-					continue
-				}
-
-				err := newIngester.Pop(collection, bucket, rec.Object, rec.Text)
-
 				if err != nil {
 					addBulkError(&errs, rec, err)
+					continue
+				}
+				e := fn(newIngester, rec)
+				if e != nil {
+					addBulkError(&errs, rec, e)
 				}
 			}
 
-			if newIngester != nil {
+			if err == nil {
 				_ = newIngester.Quit()
 			}
-
 			bulkErrorChan <- errs
-		}(i.driver, collection, bucket, r, bulkErrorChan)
+		}(r, bulkErrorChan)
 	}
 
 	errs = make([]IngestBulkError, 0)
@@ -259,73 +374,6 @@ func (i ingesterChannel) BulkPop(collection, bucket string, parallelRoutines int
 	return errs
 }
 
-func (i ingesterChannel) Count(collection, bucket, object string) (cnt int, err error) {
-	err = i.write(fmt.Sprintf("%s %s %s", count, collection, buildCountQuery(bucket, object)))
-	if err != nil {
-		return 0, err
-	}
-
-	// RESULT NUMBER
-	r, err := i.read()
-	if err != nil {
-		return 0, err
-	}
-	return strconv.Atoi(r[7:])
-}
-
-func buildCountQuery(bucket, object string) string {
-	builder := strings.Builder{}
-	if bucket != "" {
-		builder.WriteString(bucket)
-		if object != "" {
-			builder.WriteString(" " + object)
-		}
-	}
-	return builder.String()
-}
-
-func (i ingesterChannel) FlushCollection(collection string) (err error) {
-	err = i.write(fmt.Sprintf("%s %s", flushc, collection))
-	if err != nil {
-		return err
-	}
-
-	// sonic should sent OK
-	_, err = i.read()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (i ingesterChannel) FlushBucket(collection, bucket string) (err error) {
-	err = i.write(fmt.Sprintf("%s %s %s", flushb, collection, bucket))
-	if err != nil {
-		return err
-	}
-
-	// sonic should sent OK
-	_, err = i.read()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
-func (i ingesterChannel) FlushObject(collection, bucket, object string) (err error) {
-	err = i.write(fmt.Sprintf("%s %s %s %s", flusho, collection, bucket, object))
-	if err != nil {
-		return err
-	}
-
-	// sonic should sent OK
-	_, err = i.read()
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func divideIngestBulkRecords(records []IngestBulkRecord, parallelRoutines int) [][]IngestBulkRecord {
 	var divided [][]IngestBulkRecord
 	chunkSize := (len(records) + parallelRoutines - 1) / parallelRoutines
@@ -1,8 +1,13 @@
 package sonic
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
+	"reflect"
 	"runtime"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -128,3 +133,185 @@ func init() {
 		records = append(records, IngestBulkRecord{randStr(10, charset), randStr(10, charset)})
 	}
 }
+
+func TestIngesterChannel_PushError(t *testing.T) {
+	pushes := 0
+	server := newFakeServer(t, 64, func(cmd string) []string {
+		if strings.HasPrefix(cmd, "PUSH ") {
+			pushes++
+			if pushes == 3 {
+				return []string{"ERR internal_error"}
+			}
+		}
+		return []string{"OK"}
+	})
+
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword", WithPushRollback())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	text := "one two three four five six seven eight nine ten eleven twelve thirteen fourteen"
+	err = ing.Push("col", "buc", "obj", text, LangNone)
+
+	pushErr, ok := err.(*PushError)
+	if !ok {
+		t.Fatalf("expected a *PushError, got %v", err)
+	}
+	if pushErr.Pushed != 2 || pushErr.Chunks < 3 || !pushErr.RolledBack {
+		t.Errorf("unexpected push error %+v", pushErr)
+	}
+	if !errors.Is(err, pushErr.Err) {
+		t.Errorf("expected %v to unwrap to %v", err, pushErr.Err)
+	}
+
+	received := server.received()
+	for _, cmd := range received {
+		if len(cmd)+2 > 64 {
+			t.Errorf("command %q doesn't fit in the buffer", cmd)
+		}
+	}
+	pops := received[3:]
+	if len(pops) != 2 {
+		t.Fatalf("expected the 2 pushed chunks to be popped, got %q", pops)
+	}
+	for n, cmd := range pops {
+		chunk := strings.SplitN(received[n], "\"", 3)[1]
+		if cmd != `POP col buc obj "`+chunk+`"` {
+			t.Errorf("expected chunk %q to be popped, got %q", chunk, cmd)
+		}
+	}
+}
+
+func TestIngesterChannel_Replace(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		if strings.HasPrefix(cmd, "FLUSHO ") {
+			// leave time to the other replaces to interleave their commands
+			time.Sleep(5 * time.Millisecond)
+			return []string{"RESULT 1"}
+		}
+		return []string{"OK"}
+	})
+
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	err = ing.Replace("col", "buc", "obj", "spider man", LangNone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`FLUSHO col buc obj`, `PUSH col buc obj "spider man" LANG(none)`}
+	if got := server.received(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	var wg sync.WaitGroup
+	for n := 0; n < 5; n++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if err := ing.Replace("col", "buc", "obj", fmt.Sprintf("text%d", n), LangNone); err != nil {
+				t.Error(err)
+			}
+		}(n)
+	}
+	errs := ing.BulkReplace("col", "buc", 2, []IngestBulkRecord{{"obj", "bulk1"}, {"obj", "bulk2"}}, LangNone)
+	if len(errs) != 0 {
+		t.Errorf("unexpected bulk errors %v", errs)
+	}
+	wg.Wait()
+
+	received := server.received()[2:]
+	if len(received) != 14 {
+		t.Fatalf("expected 7 replaces, got %q", received)
+	}
+	for n := 0; n < len(received); n += 2 {
+		if received[n] != "FLUSHO col buc obj" || !strings.HasPrefix(received[n+1], "PUSH col buc obj ") {
+			t.Errorf("replaces are interleaved: %q", received)
+			break
+		}
+	}
+
+	if locks := ing.(ingesterChannel).locks; len(locks.locks) != 0 {
+		t.Errorf("expected the object locks to be released, got %d", len(locks.locks))
+	}
+}
+
+func TestIngesterChannel_BulkForkError(t *testing.T) {
+	server := newFakeServer(t, 20000, func(string) []string { return []string{"OK"} })
+
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	server.setPassword("rotated")
+	errs := ing.BulkPush("col", "buc", 2, []IngestBulkRecord{{"obj1", "one"}, {"obj2", "two"}}, LangNone)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 bulk errors, got %v", errs)
+	}
+	for _, e := range errs {
+		if !errors.Is(e.Error, ErrAuthFailed) {
+			t.Errorf("%s: got error %v, want %v", e.Object, e.Error, ErrAuthFailed)
+		}
+	}
+}
+
+const (
+	updateOldText = "the quick brown fox"
+	updateNewText = `The "quick" fox jumps over a lazy dog, then runs away into the dark forest`
+)
+
+// checkUpdate check the commands sent to update updateOldText to updateNewText:
+// the added terms pushed in chunks fitting in the buffer, then the removed ones popped.
+func checkUpdate(t *testing.T, received []string, buffer int) {
+	t.Helper()
+	if len(received) < 3 {
+		t.Fatalf("expected the added terms to be pushed in several chunks, got %q", received)
+	}
+	pushed := ""
+	for _, cmd := range received[:len(received)-1] {
+		if len(cmd)+2 > buffer {
+			t.Errorf("command %q doesn't fit in the buffer", cmd)
+		}
+		if !strings.HasPrefix(cmd, `PUSH col buc obj "`) || !strings.HasSuffix(cmd, `" LANG(eng)`) {
+			t.Fatalf("expected a push of the added terms, got %q", cmd)
+		}
+		pushed += strings.TrimSuffix(strings.TrimPrefix(cmd, `PUSH col buc obj "`), `" LANG(eng)`)
+	}
+	if want := "jumps over a lazy dog then runs away into dark forest"; pushed != want {
+		t.Errorf("got pushed terms %q, want %q", pushed, want)
+	}
+	if got, want := received[len(received)-1], `POP col buc obj "brown"`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestIngesterChannel_Update(t *testing.T) {
+	server := newFakeServer(t, 64, func(string) []string { return []string{"OK"} })
+
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	if err := ing.Update("col", "buc", "obj", updateOldText, updateNewText, LangEng); err != nil {
+		t.Fatal(err)
+	}
+	checkUpdate(t, server.received(), 64)
+
+	// the same terms differently written don't change anything
+	sent := len(server.received())
+	if err := ing.Update("col", "buc", "obj", "The fox, the FOX!", "fox the", LangEng); err != nil {
+		t.Fatal(err)
+	}
+	if received := server.received(); len(received) != sent {
+		t.Errorf("expected nothing to be sent, got %q", received[sent:])
+	}
+}
@@ -0,0 +1,110 @@
+package sonic
+
+import "strings"
+
+// Command is a command about to be sent to sonic, it is given to the interceptors.
+type Command struct {
+	Channel Channel
+
+	// Name of the command, eg. QUERY or PUSH.
+	Name string
+
+	// Collection and Bucket are empty for commands which don't have them, eg. PING.
+	Collection string
+	Bucket     string
+
+	// Args are the remaining arguments as sent to sonic, eg. `id:1 "Spider man" LANG(eng)` for PUSH.
+	Args string
+}
+
+// commandsWithCollection are the commands whose first argument is a collection.
+var commandsWithCollection = map[string]bool{
+	"QUERY": true, "SUGGEST": true, "LIST": true,
+	"PUSH": true, "POP": true, "COUNT": true, "FLUSHC": true, "FLUSHB": true, "FLUSHO": true,
+}
+
+// commandsWithBucket are the commands whose second argument is a bucket.
+var commandsWithBucket = map[string]bool{
+	"QUERY": true, "SUGGEST": true, "LIST": true,
+	"PUSH": true, "POP": true, "COUNT": true, "FLUSHB": true, "FLUSHO": true,
+}
+
+func parseCommand(channel Channel, cmd string) Command {
+	c := Command{Channel: channel}
+	c.Name, cmd = cutField(cmd)
+	if commandsWithCollection[c.Name] {
+		c.Collection, cmd = cutField(cmd)
+		if commandsWithBucket[c.Name] {
+			c.Bucket, cmd = cutField(cmd)
+		}
+	}
+	c.Args = cmd
+	return c
+}
+
+func cutField(s string) (field, rest string) {
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, ""
+}
+
+// String return the command as sent to sonic.
+func (c Command) String() string {
+	parts := make([]string, 0, 4)
+	for _, part := range []string{c.Name, c.Collection, c.Bucket, c.Args} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Invoker send a command and return the last line of the sonic answer,
+// eg. OK, RESULT 3 or EVENT QUERY <id> <results...> for the search commands.
+type Invoker func(cmd Command) (reply string, err error)
+
+// Interceptor is called instead of sending a command, next sends it.
+// It can change the command, answer without calling next, retry or handle the error.
+//
+// Interceptors must not use the channel they are given to, it would deadlock.
+//
+//	audit := func(cmd sonic.Command, next sonic.Invoker) (string, error) {
+//		if cmd.Channel == sonic.Ingest {
+//			log.Println(cmd)
+//		}
+//		return next(cmd)
+//	}
+type Interceptor func(cmd Command, next Invoker) (reply string, err error)
+
+// WithInterceptors run the commands of the channel through interceptors,
+// the first one is the outermost. Hooks see the commands as given to the last next.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(d *driver) {
+		d.interceptors = append(d.interceptors, interceptors...)
+	}
+}
+
+// DryRun is an interceptor answering the commands altering the index (push, pop, flush and trigger)
+// without sending them, the others are sent.
+func DryRun(cmd Command, next Invoker) (string, error) {
+	switch cmd.Name {
+	case "PUSH", "TRIGGER":
+		return "OK", nil
+	case "POP", "FLUSHC", "FLUSHB", "FLUSHO":
+		return "RESULT 0", nil
+	}
+	return next(cmd)
+}
+
+// chain return an invoker running cmd through the interceptors then send.
+func chain(interceptors []Interceptor, send Invoker) Invoker {
+	invoker := send
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		invoker = func(cmd Command) (string, error) {
+			return interceptor(cmd, next)
+		}
+	}
+	return invoker
+}
@@ -0,0 +1,73 @@
+package sonic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want Command
+	}{
+		{`PUSH movies general id:1 "Spider man" LANG(eng)`, Command{Ingest, "PUSH", "movies", "general", `id:1 "Spider man" LANG(eng)`}},
+		{"FLUSHC movies", Command{Ingest, "FLUSHC", "movies", "", ""}},
+		{"TRIGGER consolidate", Command{Ingest, "TRIGGER", "", "", "consolidate"}},
+		{"PING", Command{Ingest, "PING", "", "", ""}},
+	}
+	for _, tt := range tests {
+		got := parseCommand(Ingest, tt.cmd)
+		if got != tt.want {
+			t.Errorf("%q: expected %+v, got %+v", tt.cmd, tt.want, got)
+		}
+		if got.String() != tt.cmd {
+			t.Errorf("%q: rebuilt as %q", tt.cmd, got.String())
+		}
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(map[string]string{"acme_movies general": "id:1"}))
+
+	var order []string
+	prefix := func(cmd Command, next Invoker) (string, error) {
+		order = append(order, "prefix")
+		if cmd.Collection != "" {
+			cmd.Collection = "acme_" + cmd.Collection
+		}
+		return next(cmd)
+	}
+	audit := func(cmd Command, next Invoker) (string, error) {
+		order = append(order, "audit "+cmd.Collection)
+		return next(cmd)
+	}
+
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword", WithInterceptors(prefix, audit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := search.Query("movies", "general", "man", 10, 0, LangNone)
+	if err != nil || !reflect.DeepEqual(results, []string{"id:1"}) {
+		t.Errorf("expected the query on the prefixed collection, got %v %v", results, err)
+	}
+	if !reflect.DeepEqual(order, []string{"prefix", "audit acme_movies"}) {
+		t.Errorf("unexpected interceptors order %v", order)
+	}
+
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword", WithInterceptors(DryRun))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ing.Push("movies", "general", "id:2", "Batman", LangNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := ing.FlushCollection("movies"); err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range server.received() {
+		if !strings.HasPrefix(cmd, "QUERY") {
+			t.Errorf("expected the dry-run commands not to be sent, got %q", cmd)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package sonic
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidCursor is throw when a cursor can't be decoded.
+var ErrInvalidCursor = errors.New("invalid query cursor")
+
+// QueryIterator page through all the results of a query.
+// Pages are fetched with OFFSET until sonic returns an empty one,
+// so it works even if the server returns fewer results than the page size.
+// Objects already returned by the iterator are skipped.
+//
+//	it := sonic.NewQueryIterator(search, "movies", "general", "man", 20, sonic.LangAutoDetect)
+//	for it.Next() {
+//		fmt.Println(it.Value())
+//	}
+//	if it.Err() != nil {
+//		// handle error
+//	}
+type QueryIterator struct {
+	search     Searchable
+	collection string
+	bucket     string
+	terms      string
+	lang       Lang
+	cursor     queryCursor
+
+	// page holds the results after cursor.Offset
+	page  []string
+	value string
+	seen  map[string]struct{}
+	done  bool
+	err   error
+}
+
+// queryCursor is the position of an iteration. The query isn't part of it,
+// so a client can't change the collection or bucket it pages through, nor read the terms.
+type queryCursor struct {
+	PageSize int `json:"p"`
+
+	// Offset of the next result to return
+	Offset int `json:"o"`
+}
+
+// NewQueryIterator create an iterator over the results of a query, fetching pageSize results at a time.
+// pageSize default to 10 and is capped to QueryLimitMaximum.
+func NewQueryIterator(s Searchable, collection, bucket, terms string, pageSize int, lang Lang) *QueryIterator {
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+	if pageSize > QueryLimitMaximum {
+		pageSize = QueryLimitMaximum
+	}
+	return newQueryIterator(s, collection, bucket, terms, lang, queryCursor{PageSize: pageSize})
+}
+
+// ResumeQueryIterator create an iterator from a cursor returned by QueryIterator.Cursor.
+// The query must be the one of the iterator which returned the cursor.
+// The iteration continues after the last value returned before the cursor was taken.
+func ResumeQueryIterator(s Searchable, collection, bucket, terms string, lang Lang, cursor string) (*QueryIterator, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c queryCursor
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	err = d.Decode(&c)
+	if err != nil || c.PageSize <= 0 || c.PageSize > QueryLimitMaximum || c.Offset < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return newQueryIterator(s, collection, bucket, terms, lang, c), nil
+}
+
+func newQueryIterator(s Searchable, collection, bucket, terms string, lang Lang, c queryCursor) *QueryIterator {
+	return &QueryIterator{
+		search:     s,
+		collection: collection,
+		bucket:     bucket,
+		terms:      terms,
+		lang:       lang,
+		cursor:     c,
+		seen:       make(map[string]struct{}),
+	}
+}
+
+// Next advance to the next result, it returns false when the results are exhausted or an error occurred.
+func (it *QueryIterator) Next() bool {
+	for !it.done {
+		if len(it.page) == 0 {
+			it.fetch()
+			continue
+		}
+
+		value := it.page[0]
+		it.page = it.page[1:]
+		it.cursor.Offset++
+		if _, ok := it.seen[value]; ok {
+			continue
+		}
+		it.seen[value] = struct{}{}
+		it.value = value
+		return true
+	}
+	return false
+}
+
+func (it *QueryIterator) fetch() {
+	c := it.cursor
+	it.page, it.err = it.search.Query(it.collection, it.bucket, it.terms, c.PageSize, c.Offset, it.lang)
+	if it.err != nil || len(it.page) == 0 {
+		it.done = true
+	}
+}
+
+// Value return the current result.
+func (it *QueryIterator) Value() string {
+	return it.value
+}
+
+// Err return the error which stopped the iteration, if any.
+func (it *QueryIterator) Err() error {
+	return it.err
+}
+
+// Cursor return an opaque token to resume the iteration after the current result
+// with ResumeQueryIterator, eg. to be handed to API clients for stateless pagination.
+// It only holds the position in the results, the query is given again to ResumeQueryIterator.
+// Duplicates are only skipped within an iterator: a resumed iterator may return
+// an object already returned before the cursor was taken.
+func (it *QueryIterator) Cursor() string {
+	b, _ := json.Marshal(it.cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,83 @@
+package sonic
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pagedReplies reply to QUERY commands with a page of results, capping LIMIT to max.
+func pagedReplies(results []string, max int) func(cmd string) []string {
+	return func(cmd string) []string {
+		var limit, offset int
+		_, _ = fmt.Sscanf(cmd[strings.Index(cmd, "LIMIT("):], "LIMIT(%d) OFFSET(%d)", &limit, &offset)
+		if limit > max {
+			limit = max
+		}
+		line := "EVENT QUERY q1"
+		for i := offset; i < offset+limit && i < len(results); i++ {
+			line += " " + results[i]
+		}
+		return []string{"PENDING q1", line}
+	}
+}
+
+func TestQueryIterator(t *testing.T) {
+	server := newFakeServer(t, 20000, pagedReplies([]string{"a", "b", "c", "b", "d", "e", "f"}, 2))
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+
+	it := NewQueryIterator(search, "movies", "general", "man", 3, LangNone)
+	var got []string
+	for it.Next() {
+		got = append(got, it.Value())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if want := []string{"a", "b", "c", "d", "e", "f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	it = NewQueryIterator(search, "movies", "general", "man", 3, LangNone)
+	got = nil
+	for len(got) < 4 && it.Next() {
+		got = append(got, it.Value())
+	}
+	it, err = ResumeQueryIterator(search, "movies", "general", "man", LangNone, it.Cursor())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for it.Next() {
+		got = append(got, it.Value())
+	}
+	if want := []string{"a", "b", "c", "d", "e", "f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v after resuming, want %v", got, want)
+	}
+
+	// a cursor only holds the position, one naming another collection is refused
+	tampered := base64.RawURLEncoding.EncodeToString([]byte(`{"c":"other","b":"general","p":3,"o":4}`))
+	for _, cursor := range []string{"garbage!", tampered, base64.RawURLEncoding.EncodeToString([]byte(`{"p":1000,"o":0}`))} {
+		if _, err := ResumeQueryIterator(search, "movies", "general", "man", LangNone, cursor); err != ErrInvalidCursor {
+			t.Errorf("%s: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+	}
+	for _, cmd := range server.received() {
+		if !strings.HasPrefix(cmd, `QUERY movies general "man"`) {
+			t.Errorf("unexpected command %q", cmd)
+		}
+	}
+
+	// the page size is capped to the LIMIT accepted by sonic
+	it = NewQueryIterator(search, "movies", "general", "man", 500, LangNone)
+	it.Next()
+	received := server.received()
+	if last := received[len(received)-1]; !strings.Contains(last, "LIMIT(100) OFFSET(0)") {
+		t.Errorf("expected the page size to be capped, got %q", last)
+	}
+}
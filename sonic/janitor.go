@@ -0,0 +1,157 @@
+package sonic
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// JanitorStats are the counters of a Janitor.
+type JanitorStats struct {
+	// Reported is the number of stale objects received.
+	Reported uint64
+
+	// Pruned is the number of objects flushed from the index.
+	Pruned uint64
+
+	// Failed is the number of FLUSHO commands which failed.
+	Failed uint64
+
+	// Dropped is the number of objects dropped because the queue was full.
+	Dropped uint64
+}
+
+type staleObject struct {
+	collection, bucket, object string
+}
+
+// Janitor prune in background the stale objects found during searches,
+// ie. objects returned by sonic whose entities don't exist anymore.
+// Its Report method is a StaleFunc, so it can be given to QueryInto.
+//
+// Objects are batched and flushed with FLUSHO on the given ingest channel,
+// which can still be used meanwhile.
+type Janitor struct {
+	// first field for 64-bit alignment of the atomic counters
+	stats JanitorStats
+
+	ingester  Ingestable
+	batchSize int
+	interval  time.Duration
+
+	queue chan staleObject
+
+	// mu guard closed, so no object is queued once Close drained the queue
+	mu     sync.RWMutex
+	closed bool
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewJanitor start a Janitor flushing the stale objects by batches of batchSize,
+// or every interval if the batch isn't full, default to 10 seconds.
+func NewJanitor(ingester Ingestable, batchSize int, interval time.Duration) *Janitor {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	j := &Janitor{
+		ingester:  ingester,
+		batchSize: batchSize,
+		interval:  interval,
+		queue:     make(chan staleObject, 16*batchSize),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go j.run()
+	return j
+}
+
+// Report queue stale objects to be pruned. It never blocks:
+// if the queue is full or the janitor is closed the objects are dropped,
+// they will be reported again by a later search.
+func (j *Janitor) Report(collection, bucket string, objects []string) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	for _, object := range objects {
+		atomic.AddUint64(&j.stats.Reported, 1)
+		if j.closed {
+			atomic.AddUint64(&j.stats.Dropped, 1)
+			continue
+		}
+		select {
+		case j.queue <- staleObject{collection, bucket, object}:
+		default:
+			atomic.AddUint64(&j.stats.Dropped, 1)
+		}
+	}
+}
+
+// Stats return a snapshot of the counters.
+func (j *Janitor) Stats() JanitorStats {
+	return JanitorStats{
+		Reported: atomic.LoadUint64(&j.stats.Reported),
+		Pruned:   atomic.LoadUint64(&j.stats.Pruned),
+		Failed:   atomic.LoadUint64(&j.stats.Failed),
+		Dropped:  atomic.LoadUint64(&j.stats.Dropped),
+	}
+}
+
+// Close flush the queued objects and stop the janitor.
+// The ingest channel isn't closed.
+func (j *Janitor) Close() {
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return
+	}
+	j.closed = true
+	j.mu.Unlock()
+
+	close(j.stop)
+	<-j.done
+}
+
+func (j *Janitor) run() {
+	defer close(j.done)
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	batch := make(map[staleObject]struct{}, j.batchSize)
+	for {
+		select {
+		case o := <-j.queue:
+			batch[o] = struct{}{}
+			if len(batch) >= j.batchSize {
+				j.prune(batch)
+			}
+		case <-ticker.C:
+			j.prune(batch)
+		case <-j.stop:
+			for {
+				select {
+				case o := <-j.queue:
+					batch[o] = struct{}{}
+				default:
+					j.prune(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// prune flush the objects of the batch and empty it.
+func (j *Janitor) prune(batch map[staleObject]struct{}) {
+	for o := range batch {
+		err := j.ingester.FlushObject(o.collection, o.bucket, o.object)
+		if err != nil {
+			atomic.AddUint64(&j.stats.Failed, 1)
+		} else {
+			atomic.AddUint64(&j.stats.Pruned, 1)
+		}
+		delete(batch, o)
+	}
+}
@@ -0,0 +1,45 @@
+package sonic
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestJanitor(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		return []string{"OK"}
+	})
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+
+	j := NewJanitor(ing, 2, time.Hour)
+	j.Report("movies", "general", []string{"id:1", "id:1", "id:2"})
+	j.Report("movies", "other", []string{"id:3"})
+	j.Close()
+
+	received := server.received()
+	sort.Strings(received)
+	want := []string{"FLUSHO movies general id:1", "FLUSHO movies general id:2", "FLUSHO movies other id:3"}
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("got %v, want %v", received, want)
+	}
+	stats := j.Stats()
+	if stats != (JanitorStats{Reported: 4, Pruned: 3}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	// objects reported once closed are dropped
+	j.Report("movies", "general", []string{"id:4"})
+	j.Close()
+	if stats := j.Stats(); stats != (JanitorStats{Reported: 5, Pruned: 3, Dropped: 1}) {
+		t.Errorf("unexpected stats after Close %+v", stats)
+	}
+
+	// a non-positive interval has a default
+	NewJanitor(ing, 1, 0).Close()
+}
@@ -0,0 +1,45 @@
+package sonic
+
+import "sync"
+
+// objectLocks serialize operations on the same object.
+// Locks are reference counted so the map doesn't grow with every object ever seen.
+type objectLocks struct {
+	mu    sync.Mutex
+	locks map[string]*objectLock
+}
+
+type objectLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newObjectLocks() *objectLocks {
+	return &objectLocks{locks: make(map[string]*objectLock)}
+}
+
+// lock acquire the lock of the given object and return the function releasing it.
+func (o *objectLocks) lock(collection, bucket, object string) (unlock func()) {
+	key := collection + "\x00" + bucket + "\x00" + object
+
+	o.mu.Lock()
+	l, ok := o.locks[key]
+	if !ok {
+		l = &objectLock{}
+		o.locks[key] = l
+	}
+	l.refs++
+	o.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		o.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(o.locks, key)
+		}
+		o.mu.Unlock()
+	}
+}
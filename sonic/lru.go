@@ -0,0 +1,108 @@
+package sonic
+
+import (
+	linkedlist "container/list"
+	"sync"
+	"time"
+)
+
+// lru is a size bounded cache evicting the least recently used entries,
+// entries also expire after ttl if ttl > 0. It is safe for concurrent use.
+type lru struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*linkedlist.Element
+	order   *linkedlist.List
+	// tagged index the keys of the entries by tag, so removeTag doesn't scan every entry
+	tagged map[string]map[string]struct{}
+}
+
+type lruEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+	tags    []string
+}
+
+func newLRU(size int, ttl time.Duration) *lru {
+	if size <= 0 {
+		size = 1
+	}
+	return &lru{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*linkedlist.Element),
+		order:   linkedlist.New(),
+		tagged:  make(map[string]map[string]struct{}),
+	}
+}
+
+func (c *lru) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*lruEntry)
+	if c.ttl > 0 && time.Now().After(e.expires) {
+		c.remove(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// set add or replace the entry of key, it can be removed later with removeTag by any of its tags.
+func (c *lru) set(key string, value interface{}, tags ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: time.Now().Add(c.ttl), tags: tags})
+	for _, tag := range tags {
+		keys, ok := c.tagged[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.tagged[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// removeTag remove the entries set with tag.
+func (c *lru) removeTag(tag string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.tagged[tag] {
+		c.remove(c.entries[key])
+	}
+}
+
+// remove drop an entry and its tags, c.mu must be held.
+func (c *lru) remove(el *linkedlist.Element) {
+	e := c.order.Remove(el).(*lruEntry)
+	delete(c.entries, e.key)
+	for _, tag := range e.tags {
+		keys := c.tagged[tag]
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.tagged, tag)
+		}
+	}
+}
+
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
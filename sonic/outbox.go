@@ -0,0 +1,520 @@
+package sonic
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrOutboxClosed is throw when a command is sent to a closed outbox.
+	ErrOutboxClosed = errors.New("sonic outbox is closed")
+
+	// ErrOutboxCommand is throw when an outbox entry has an unknown command.
+	ErrOutboxCommand = errors.New("unknown outbox command")
+)
+
+// OutboxEntry is an ingest command persisted in the outbox, waiting to be replayed.
+type OutboxEntry struct {
+	Seq        uint64 `json:"seq"`
+	Command    string `json:"cmd"`
+	Collection string `json:"collection,omitempty"`
+	Bucket     string `json:"bucket,omitempty"`
+	Object     string `json:"object,omitempty"`
+	Text       string `json:"text,omitempty"`
+	Lang       Lang   `json:"lang,omitempty"`
+}
+
+// OutboxFailure is an entry refused by sonic during a replay, it won't be sent again.
+type OutboxFailure struct {
+	Entry OutboxEntry
+	Err   error
+}
+
+// outboxAck is the name of the record marking an entry as replayed.
+const outboxAck = "ACK"
+
+// Outbox is a write-ahead log in front of an ingest channel.
+// PUSH, POP and FLUSH commands are appended to a local file and acknowledged
+// immediately, then replayed in order as soon as sonic is reachable.
+// Delivery is at-least-once: an entry may be sent again after a crash.
+// A PUSH sent twice is harmless, but a POP or FLUSH sent again also removes
+// what was written by other clients since its first delivery.
+//
+// Replay stops while sonic can't be reached and resumes from the same entry.
+// Entries sonic refuses, eg. with an ERR reply, would never succeed: they are
+// dropped from the log and reported by Failed, so the following ones aren't stuck.
+//
+// Count and Ping need sonic and are executed directly on the ingest channel.
+// Outbox is safe for concurrent use.
+type Outbox struct {
+	Host     string
+	Port     int
+	Password string
+
+	opts    []Option
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	seq     uint64
+	pending []OutboxEntry
+	failed  []OutboxFailure
+	closed  bool
+
+	// replayMu serialize replays and protect ingester
+	replayMu sync.Mutex
+	ingester Ingestable
+
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// NewOutbox open (or create) the outbox file at path.
+// Pending entries from a previous run are loaded and the file is compacted.
+// Nothing is sent until Replay is called or the background replay is started with Start.
+// The options are used to open the ingest channel.
+func NewOutbox(path string, host string, port int, password string, opts ...Option) (*Outbox, error) {
+	o := &Outbox{
+		Host:     host,
+		Port:     port,
+		Password: password,
+		opts:     opts,
+		path:     path,
+		notify:   make(chan struct{}, 1),
+	}
+
+	err := o.load()
+	if err != nil {
+		return nil, err
+	}
+	err = o.Compact()
+	if err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// load read the outbox file and rebuild the pending entries.
+func (o *Outbox) load() error {
+	f, err := os.Open(o.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var entries []OutboxEntry
+	acked := make(map[uint64]bool)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<30)
+	var torn error
+	for line := 1; scanner.Scan(); line++ {
+		if torn != nil {
+			// only the last line may be partially written
+			return torn
+		}
+		var e OutboxEntry
+		err := json.Unmarshal(scanner.Bytes(), &e)
+		if err != nil {
+			torn = fmt.Errorf("corrupted outbox %s at line %d: %v", o.path, line, err)
+			continue
+		}
+		if e.Seq > o.seq {
+			o.seq = e.Seq
+		}
+		if e.Command == outboxAck {
+			acked[e.Seq] = true
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if !acked[e.Seq] {
+			o.pending = append(o.pending, e)
+		}
+	}
+	return nil
+}
+
+// Compact rewrite the outbox file with only the pending entries.
+func (o *Outbox) Compact() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOutboxClosed
+	}
+
+	tmp := o.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, e := range o.pending {
+		err = enc.Encode(e)
+		if err != nil {
+			_ = f.Close()
+			return err
+		}
+	}
+	err = w.Flush()
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = f.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	if o.file != nil {
+		_ = o.file.Close()
+		o.file = nil
+	}
+	err = os.Rename(tmp, o.path)
+	if err != nil {
+		return err
+	}
+	o.file, err = os.OpenFile(o.path, os.O_APPEND|os.O_WRONLY, 0600)
+	return err
+}
+
+// Pending return a copy of the entries not replayed yet, in order.
+func (o *Outbox) Pending() []OutboxEntry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]OutboxEntry(nil), o.pending...)
+}
+
+// Failed return the entries refused by sonic since the outbox was opened, in order.
+func (o *Outbox) Failed() []OutboxFailure {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]OutboxFailure(nil), o.failed...)
+}
+
+// Len return the number of entries not replayed yet.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// append persist the entries, the outbox file is synced once all of them are written.
+func (o *Outbox) append(entries ...OutboxEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOutboxClosed
+	}
+
+	buf, err := o.encode(entries)
+	if err != nil {
+		return err
+	}
+	_, err = o.file.Write(buf)
+	if err == nil {
+		err = o.file.Sync()
+	}
+	if err != nil {
+		return err
+	}
+	o.pending = append(o.pending, entries...)
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// encode assign a sequence number to the entries and marshal them as JSON lines.
+func (o *Outbox) encode(entries []OutboxEntry) ([]byte, error) {
+	var buf []byte
+	for i := range entries {
+		o.seq++
+		entries[i].Seq = o.seq
+		b, err := json.Marshal(entries[i])
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b...)
+		buf = append(buf, '\n')
+	}
+	return buf, nil
+}
+
+// ack mark the first pending entry as replayed.
+// Acks aren't synced: losing one only means replaying the entry again.
+func (o *Outbox) ack(seq uint64) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.pending) > 0 && o.pending[0].Seq == seq {
+		o.pending = o.pending[1:]
+	}
+	if o.file == nil {
+		return ErrOutboxClosed
+	}
+	b, err := json.Marshal(OutboxEntry{Seq: seq, Command: outboxAck})
+	if err != nil {
+		return err
+	}
+	_, err = o.file.Write(append(b, '\n'))
+	return err
+}
+
+// Replay send the pending entries in order, stopping when sonic can't be reached.
+// Entries refused by sonic are acknowledged and added to Failed.
+// A connection to sonic is opened if there is none.
+func (o *Outbox) Replay() error {
+	o.replayMu.Lock()
+	defer o.replayMu.Unlock()
+
+	for {
+		o.mu.Lock()
+		if len(o.pending) == 0 {
+			o.mu.Unlock()
+			return nil
+		}
+		e := o.pending[0]
+		o.mu.Unlock()
+
+		err := o.connect()
+		if err != nil {
+			return err
+		}
+		err = o.send(e)
+		if err != nil && !outboxRejected(err) {
+			// the connection may be broken, start from a new one next time
+			_ = o.ingester.Quit()
+			o.ingester = nil
+			return err
+		}
+		if err != nil {
+			o.mu.Lock()
+			o.failed = append(o.failed, OutboxFailure{e, err})
+			o.mu.Unlock()
+		}
+		err = o.ack(e.Seq)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// connect open the ingest channel used for replays. replayMu must be held.
+func (o *Outbox) connect() error {
+	if o.ingester != nil {
+		return nil
+	}
+	ingester, err := NewIngester(o.Host, o.Port, o.Password, o.opts...)
+	if err != nil {
+		return err
+	}
+	o.ingester = ingester
+	return nil
+}
+
+func (o *Outbox) send(e OutboxEntry) error {
+	switch ingesterCommands(e.Command) {
+	case push:
+		return o.ingester.Push(e.Collection, e.Bucket, e.Object, e.Text, e.Lang)
+	case pop:
+		return o.ingester.Pop(e.Collection, e.Bucket, e.Object, e.Text)
+	case flushc:
+		return o.ingester.FlushCollection(e.Collection)
+	case flushb:
+		return o.ingester.FlushBucket(e.Collection, e.Bucket)
+	case flusho:
+		return o.ingester.FlushObject(e.Collection, e.Bucket, e.Object)
+	}
+	return fmt.Errorf("%w %q", ErrOutboxCommand, e.Command)
+}
+
+// outboxRejected report whether an entry failed because of its content rather than the connection,
+// so sending it again would fail the same way.
+func outboxRejected(err error) bool {
+	return !isConnectionError(err) || errors.Is(err, ErrCommandTooLong) || errors.Is(err, ErrOutboxCommand)
+}
+
+// Start replay the pending entries in background, every interval and
+// as soon as new entries are appended. It stops when the outbox is closed.
+func (o *Outbox) Start(interval time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.stop != nil || o.closed {
+		return
+	}
+	o.stop = make(chan struct{})
+	o.done = make(chan struct{})
+
+	go func() {
+		defer close(o.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			_ = o.Replay()
+			select {
+			case <-o.stop:
+				return
+			case <-ticker.C:
+			case <-o.notify:
+			}
+		}
+	}()
+}
+
+// Close stop the background replay, quit the ingest channel and close the outbox file.
+// Pending entries are kept in the file and loaded by the next NewOutbox.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	stop, done := o.stop, o.done
+	o.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+
+	o.replayMu.Lock()
+	if o.ingester != nil {
+		_ = o.ingester.Quit()
+		o.ingester = nil
+	}
+	o.replayMu.Unlock()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
+
+func (o *Outbox) Push(collection, bucket, object, text string, lang Lang) (err error) {
+	return o.append(OutboxEntry{Command: string(push), Collection: collection, Bucket: bucket, Object: object, Text: text, Lang: lang})
+}
+
+func (o *Outbox) BulkPush(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	entries := make([]OutboxEntry, 0, len(records))
+	for _, rec := range records {
+		entries = append(entries, OutboxEntry{Command: string(push), Collection: collection, Bucket: bucket, Object: rec.Object, Text: rec.Text, Lang: lang})
+	}
+	return o.appendBulk(records, entries)
+}
+
+func (o *Outbox) Pop(collection, bucket, object, text string) (err error) {
+	return o.append(OutboxEntry{Command: string(pop), Collection: collection, Bucket: bucket, Object: object, Text: text})
+}
+
+func (o *Outbox) BulkPop(collection, bucket string, parallelRoutines int, records []IngestBulkRecord) []IngestBulkError {
+	entries := make([]OutboxEntry, 0, len(records))
+	for _, rec := range records {
+		entries = append(entries, OutboxEntry{Command: string(pop), Collection: collection, Bucket: bucket, Object: rec.Object, Text: rec.Text})
+	}
+	return o.appendBulk(records, entries)
+}
+
+// appendBulk persist the entries of a bulk operation at once.
+// If it fails, every record is reported with the error.
+func (o *Outbox) appendBulk(records []IngestBulkRecord, entries []OutboxEntry) []IngestBulkError {
+	err := o.append(entries...)
+	if err != nil {
+		return bulkErrors(records, err)
+	}
+	return make([]IngestBulkError, 0)
+}
+
+// Count is executed directly on sonic, pending entries aren't taken into account.
+func (o *Outbox) Count(collection, bucket, object string) (count int, err error) {
+	o.replayMu.Lock()
+	defer o.replayMu.Unlock()
+	err = o.connect()
+	if err != nil {
+		return 0, err
+	}
+	return o.ingester.Count(collection, bucket, object)
+}
+
+func (o *Outbox) FlushCollection(collection string) (err error) {
+	return o.append(OutboxEntry{Command: string(flushc), Collection: collection})
+}
+
+func (o *Outbox) FlushBucket(collection, bucket string) (err error) {
+	return o.append(OutboxEntry{Command: string(flushb), Collection: collection, Bucket: bucket})
+}
+
+func (o *Outbox) FlushObject(collection, bucket, object string) (err error) {
+	return o.append(OutboxEntry{Command: string(flusho), Collection: collection, Bucket: bucket, Object: object})
+}
+
+// Replace append the FLUSHO and PUSH entries at once so they are replayed one after the other.
+func (o *Outbox) Replace(collection, bucket, object, text string, lang Lang) (err error) {
+	return o.append(
+		OutboxEntry{Command: string(flusho), Collection: collection, Bucket: bucket, Object: object},
+		OutboxEntry{Command: string(push), Collection: collection, Bucket: bucket, Object: object, Text: text, Lang: lang},
+	)
+}
+
+func (o *Outbox) BulkReplace(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	entries := make([]OutboxEntry, 0, 2*len(records))
+	for _, rec := range records {
+		entries = append(entries,
+			OutboxEntry{Command: string(flusho), Collection: collection, Bucket: bucket, Object: rec.Object},
+			OutboxEntry{Command: string(push), Collection: collection, Bucket: bucket, Object: rec.Object, Text: rec.Text, Lang: lang},
+		)
+	}
+	return o.appendBulk(records, entries)
+}
+
+// Update compute the terms difference right away and append the resulting PUSH and POP entries.
+func (o *Outbox) Update(collection, bucket, object, oldText, newText string, lang Lang) (err error) {
+	added, removed := diffTerms(oldText, newText)
+
+	var entries []OutboxEntry
+	if len(added) > 0 {
+		entries = append(entries, OutboxEntry{Command: string(push), Collection: collection, Bucket: bucket, Object: object, Text: joinTerms(added), Lang: lang})
+	}
+	if len(removed) > 0 {
+		entries = append(entries, OutboxEntry{Command: string(pop), Collection: collection, Bucket: bucket, Object: object, Text: joinTerms(removed)})
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	return o.append(entries...)
+}
+
+// Quit close the outbox, see Close.
+func (o *Outbox) Quit() (err error) {
+	return o.Close()
+}
+
+// Ping ping sonic through the ingest channel used for replays.
+func (o *Outbox) Ping() (err error) {
+	o.replayMu.Lock()
+	defer o.replayMu.Unlock()
+	err = o.connect()
+	if err != nil {
+		return err
+	}
+	return o.ingester.Ping()
+}
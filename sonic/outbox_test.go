@@ -0,0 +1,169 @@
+package sonic
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOutbox_PersistAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.log")
+
+	o, err := NewOutbox(path, "localhost", 1491, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Push("movies", "general", "id:1", "Star \"wars\"", LangEng); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Replace("movies", "general", "id:2", "Batman", LangNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.ack(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Push("movies", "general", "id:3", "Spider man", LangNone); err != ErrOutboxClosed {
+		t.Fatalf("expected ErrOutboxClosed, got %v", err)
+	}
+	if err := o.Compact(); err != ErrOutboxClosed {
+		t.Fatalf("expected ErrOutboxClosed, got %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("expected no temporary file after Close, got %v", err)
+	}
+
+	// simulate a crash in the middle of a write
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"seq":4,"cmd":"PU`)
+	_ = f.Close()
+
+	o, err = NewOutbox(path, "localhost", 1491, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	pending := o.Pending()
+	if len(pending) != 2 {
+		t.Fatalf("expected 2 pending entries, got %v", pending)
+	}
+	if pending[0].Command != "FLUSHO" || pending[1].Command != "PUSH" || pending[1].Text != "Batman" {
+		t.Errorf("unexpected pending entries %v", pending)
+	}
+
+	if err := o.Push("movies", "general", "id:3", "Spider man", LangNone); err != nil {
+		t.Fatal(err)
+	}
+	if last := o.Pending()[2]; last.Seq != 4 {
+		t.Errorf("expected sequence to resume at 4, got %d", last.Seq)
+	}
+}
+
+func TestOutbox_Replay(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		if strings.Contains(cmd, "poison") {
+			return []string{"ERR invalid"}
+		}
+		return []string{"OK"}
+	})
+	down := newFakeServer(t, 20000, nil)
+	down.close()
+
+	path := filepath.Join(t.TempDir(), "outbox.log")
+	o, err := NewOutbox(path, "127.0.0.1", down.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = o.Push("movies", "general", "id:1", "Spider man", LangNone)
+	_ = o.Push("movies", "general", "poison", "Batman", LangNone)
+	_ = o.Pop("movies", "general", "id:1", "man")
+	_ = o.FlushObject("movies", "general", "id:2")
+
+	// nothing is lost while sonic is down
+	if err := o.Replay(); err == nil || o.Len() != 4 {
+		t.Fatalf("expected the replay to stop with 4 pending entries, got %v and %d", err, o.Len())
+	}
+
+	// the refused entry doesn't block the following ones
+	o.Port = server.port
+	if err := o.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`PUSH movies general id:1 "Spider man" LANG(none)`,
+		`PUSH movies general poison "Batman" LANG(none)`,
+		`POP movies general id:1 "man"`,
+		`FLUSHO movies general id:2`,
+	}
+	if received := server.received(); !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+	failed := o.Failed()
+	if len(failed) != 1 || failed[0].Entry.Object != "poison" || !errors.As(failed[0].Err, new(*ServerError)) {
+		t.Errorf("expected the poison entry to fail, got %+v", failed)
+	}
+	if o.Len() != 0 {
+		t.Errorf("expected no pending entry, got %v", o.Pending())
+	}
+
+	// entries appended once started are replayed in background
+	o.Start(time.Hour)
+	_ = o.FlushBucket("movies", "general")
+	for deadline := time.Now().Add(5 * time.Second); o.Len() > 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if received := server.received(); received[len(received)-1] != "FLUSHB movies general" {
+		t.Errorf("expected the flush to be replayed, got %v", received)
+	}
+	if err := o.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the acknowledged entries aren't loaded again
+	o, err = NewOutbox(path, "127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+	if o.Len() != 0 {
+		t.Errorf("expected no pending entry after reload, got %v", o.Pending())
+	}
+}
+
+func TestOutbox_Update(t *testing.T) {
+	server := newFakeServer(t, 64, func(string) []string { return []string{"OK"} })
+
+	o, err := NewOutbox(filepath.Join(t.TempDir(), "outbox.log"), "127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer o.Close()
+
+	if err := o.Update("col", "buc", "obj", "The fox, the FOX!", "fox the", LangEng); err != nil {
+		t.Fatal(err)
+	}
+	if o.Len() != 0 {
+		t.Fatalf("expected no entry for an unchanged text, got %v", o.Pending())
+	}
+
+	if err := o.Update("col", "buc", "obj", updateOldText, updateNewText, LangEng); err != nil {
+		t.Fatal(err)
+	}
+	if pending := o.Pending(); len(pending) != 2 || pending[0].Command != "PUSH" || pending[1].Command != "POP" {
+		t.Fatalf("expected a PUSH and a POP entry, got %v", pending)
+	}
+	if err := o.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	checkUpdate(t, server.received(), 64)
+}
@@ -0,0 +1,68 @@
+package sonic
+
+import "sync"
+
+// pool keep idle connections with the settings of a driver,
+// to be reused by operations dispatched on several goroutines.
+type pool struct {
+	driver  *driver
+	maxIdle int
+
+	mu     sync.Mutex
+	idle   []*driver
+	closed bool
+}
+
+func newPool(d *driver, maxIdle int) *pool {
+	return &pool{driver: d, maxIdle: maxIdle}
+}
+
+// get return an idle connection or open a new one, ErrClosed once the pool is closed.
+func (p *pool) get() (*driver, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if n := len(p.idle); n > 0 {
+		d := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return d, nil
+	}
+	p.mu.Unlock()
+
+	d := p.driver.clone()
+	err := d.Connect()
+	if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// put give back a connection obtained with get.
+// Connections broken by err, or those in excess of maxIdle, are closed;
+// an error returned by sonic leaves the connection usable.
+func (p *pool) put(d *driver, err error) {
+	p.mu.Lock()
+	if !isConnectionError(err) && !d.isClosed() && !p.closed && len(p.idle) < p.maxIdle {
+		p.idle = append(p.idle, d)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	_ = d.Quit()
+}
+
+// close quit the idle connections, connections given back later are closed.
+func (p *pool) close() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.mu.Unlock()
+
+	for _, d := range idle {
+		_ = d.Quit()
+	}
+}
@@ -0,0 +1,145 @@
+package sonic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var (
+	// ErrQueryName is throw when a collection or bucket name is empty or contains spaces.
+	ErrQueryName = errors.New("invalid collection or bucket name")
+
+	// ErrQueryTerms is throw when a query has no terms.
+	ErrQueryTerms = errors.New("query terms are empty")
+
+	// ErrQueryLimit is throw when a query limit is out of range.
+	ErrQueryLimit = fmt.Errorf("query limit must be between 1 and %d", QueryLimitMaximum)
+
+	// ErrQueryOffset is throw when a query offset is out of range.
+	ErrQueryOffset = errors.New("query offset must be between 0 and 4294967295")
+)
+
+// QueryLimitMaximum is the greatest LIMIT of a QUERY accepted by sonic (QUERY_LIMIT_MAXIMUM),
+// a greater one is rejected.
+const QueryLimitMaximum = 100
+
+// QueryBuilder build a QUERY command, only the options which are set are sent,
+// the others take the server defaults.
+// Use Searchable.NewQuery to get one.
+type QueryBuilder struct {
+	collection, bucket string
+	terms              []string
+
+	limit, offset       int
+	hasLimit, hasOffset bool
+	lang                Lang
+	hasLang             bool
+
+	exec func(cmd string) ([]string, error)
+}
+
+// QueryResult is the result of an asynchronous query.
+type QueryResult struct {
+	Results []string
+	Err     error
+}
+
+func newQueryBuilder(collection, bucket string, exec func(cmd string) ([]string, error)) *QueryBuilder {
+	return &QueryBuilder{
+		collection: collection,
+		bucket:     bucket,
+		exec:       exec,
+	}
+}
+
+// Terms add search terms, they are joined with spaces.
+func (q *QueryBuilder) Terms(terms ...string) *QueryBuilder {
+	q.terms = append(q.terms, terms...)
+	return q
+}
+
+// Limit set the maximum number of results.
+func (q *QueryBuilder) Limit(limit int) *QueryBuilder {
+	q.limit, q.hasLimit = limit, true
+	return q
+}
+
+// Offset set the number of results to skip.
+func (q *QueryBuilder) Offset(offset int) *QueryBuilder {
+	q.offset, q.hasOffset = offset, true
+	return q
+}
+
+// Lang set the lang of the terms, LangAutoDetect unset it.
+func (q *QueryBuilder) Lang(lang Lang) *QueryBuilder {
+	q.lang, q.hasLang = lang, lang != LangAutoDetect
+	return q
+}
+
+// copyTo set the terms and options of q on dst, which may map the collection and bucket differently.
+func (q *QueryBuilder) copyTo(dst *QueryBuilder) *QueryBuilder {
+	dst.terms = append(dst.terms, q.terms...)
+	dst.limit, dst.hasLimit = q.limit, q.hasLimit
+	dst.offset, dst.hasOffset = q.offset, q.hasOffset
+	dst.lang, dst.hasLang = q.lang, q.hasLang
+	return dst
+}
+
+// Validate check the query before sending it.
+func (q *QueryBuilder) Validate() error {
+	if !isValidName(q.collection) || !isValidName(q.bucket) {
+		return ErrQueryName
+	}
+	if strings.TrimSpace(strings.Join(q.terms, "")) == "" {
+		return ErrQueryTerms
+	}
+	if q.hasLimit && (q.limit < 1 || q.limit > QueryLimitMaximum) {
+		return ErrQueryLimit
+	}
+	if q.hasOffset && (q.offset < 0 || int64(q.offset) > 4294967295) {
+		return ErrQueryOffset
+	}
+	return nil
+}
+
+// String return the command sent to sonic.
+func (q *QueryBuilder) String() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "%s %s %s \"%s\"", query, q.collection, q.bucket, escapeText(strings.Join(q.terms, " ")))
+	if q.hasLimit {
+		fmt.Fprintf(&b, " LIMIT(%d)", q.limit)
+	}
+	if q.hasOffset {
+		fmt.Fprintf(&b, " OFFSET(%d)", q.offset)
+	}
+	if q.hasLang {
+		fmt.Fprintf(&b, " LANG(%s)", q.lang)
+	}
+	return b.String()
+}
+
+// Exec validate then execute the query.
+func (q *QueryBuilder) Exec() (results []string, err error) {
+	err = q.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return q.exec(q.String())
+}
+
+// ExecAsync execute the query in a goroutine, the result is sent on the returned channel.
+// The search channel can still be used meanwhile, its commands are serialized.
+func (q *QueryBuilder) ExecAsync() <-chan QueryResult {
+	res := make(chan QueryResult, 1)
+	go func() {
+		results, err := q.Exec()
+		res <- QueryResult{results, err}
+	}()
+	return res
+}
+
+func isValidName(name string) bool {
+	return name != "" && strings.IndexFunc(name, unicode.IsSpace) == -1
+}
@@ -0,0 +1,50 @@
+package sonic
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(map[string]string{
+		"movies general": "id:1 id:2",
+	}))
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+
+	q := search.NewQuery("movies", "general").Terms("spider", "man")
+	if want := `QUERY movies general "spider man"`; q.String() != want {
+		t.Errorf("got %q, want %q", q.String(), want)
+	}
+	q.Limit(20).Offset(0).Lang(LangEng)
+	if want := `QUERY movies general "spider man" LIMIT(20) OFFSET(0) LANG(eng)`; q.String() != want {
+		t.Errorf("got %q, want %q", q.String(), want)
+	}
+
+	res := <-q.ExecAsync()
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if want := []string{"id:1", "id:2"}; !reflect.DeepEqual(res.Results, want) {
+		t.Errorf("got %v, want %v", res.Results, want)
+	}
+
+	tests := []struct {
+		q   *QueryBuilder
+		err error
+	}{
+		{search.NewQuery("movies", "").Terms("man"), ErrQueryName},
+		{search.NewQuery("movies", "general"), ErrQueryTerms},
+		{search.NewQuery("movies", "general").Terms("man").Limit(0), ErrQueryLimit},
+		{search.NewQuery("movies", "general").Terms("man").Limit(QueryLimitMaximum + 1), ErrQueryLimit},
+		{search.NewQuery("movies", "general").Terms("man").Offset(-1), ErrQueryOffset},
+	}
+	for _, tt := range tests {
+		if _, err := tt.q.Exec(); err != tt.err {
+			t.Errorf("%s: got error %v, want %v", tt.q, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,482 @@
+package sonic
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoReplica is throw when no replica is healthy.
+	ErrNoReplica = errors.New("no healthy replica")
+
+	// ErrReplicaQueueFull is throw when an asynchronous replica can't keep up
+	// and a command had to be dropped.
+	ErrReplicaQueueFull = errors.New("replica queue is full")
+)
+
+// ReplicationMode define how ingest commands are mirrored to the replicas.
+type ReplicationMode int
+
+const (
+	// ReplicateSync send every command to all replicas before returning.
+	ReplicateSync ReplicationMode = iota
+
+	// ReplicateAsync queue the commands of each replica and return immediately.
+	ReplicateAsync
+)
+
+// Replica is a sonic server of a Replicated client.
+type Replica struct {
+	Name   string
+	Search Searchable
+	Ingest Ingestable
+}
+
+// Divergence is a command which failed on a replica, leaving it out of sync with the others.
+type Divergence struct {
+	Replica    string
+	Command    string
+	Collection string
+	Bucket     string
+	Object     string
+	Err        error
+}
+
+// ReplicatedOptions configure a Replicated client.
+type ReplicatedOptions struct {
+	Mode ReplicationMode
+
+	// QueueSize is the number of commands queued per replica in asynchronous mode, default to 1024.
+	QueueSize int
+
+	// RetryInterval is how long a failed replica is skipped by reads, default to 5 seconds.
+	RetryInterval time.Duration
+
+	// OnDivergence is called when a command failed on a replica.
+	// In asynchronous mode it is called from the replica goroutine.
+	OnDivergence func(d Divergence)
+}
+
+// Replicated mirror every ingest command to several sonic servers,
+// since sonic has no replication of its own, and serve reads from a healthy replica:
+// when a read fails and the replica doesn't answer PING, the next one is used.
+// It implements Searchable and Ingestable.
+//
+// Replicated is safe for concurrent use, the channels it wraps serialize their commands.
+type Replicated struct {
+	opts     ReplicatedOptions
+	replicas []*replicaState
+
+	mu      sync.Mutex
+	current int
+
+	// closeMu guard the queues, which are closed by Quit
+	closeMu sync.RWMutex
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+type replicaState struct {
+	Replica
+
+	// queue of the asynchronous mode, commands are run by the replica goroutine
+	queue     chan func()
+	downUntil time.Time
+}
+
+// NewReplicated create a replicated client, the first replica is the preferred one for reads.
+func NewReplicated(replicas []Replica, opts ReplicatedOptions) (*Replicated, error) {
+	if len(replicas) == 0 {
+		return nil, ErrNoReplica
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+	if opts.RetryInterval <= 0 {
+		opts.RetryInterval = 5 * time.Second
+	}
+
+	r := &Replicated{opts: opts}
+	for _, replica := range replicas {
+		state := &replicaState{Replica: replica}
+		if opts.Mode == ReplicateAsync {
+			state.queue = make(chan func(), opts.QueueSize)
+			r.wg.Add(1)
+			go func() {
+				defer r.wg.Done()
+				for fn := range state.queue {
+					fn()
+				}
+			}()
+		}
+		r.replicas = append(r.replicas, state)
+	}
+	return r, nil
+}
+
+// Status return the health of each replica as seen by reads.
+func (r *Replicated) Status() map[string]bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status := make(map[string]bool, len(r.replicas))
+	now := time.Now()
+	for _, replica := range r.replicas {
+		status[replica.Name] = now.After(replica.downUntil)
+	}
+	return status
+}
+
+// CheckDivergence compare the count of a bucket, or a collection if bucket is empty, on every replica.
+// It returns the counts by replica and whether they differ.
+func (r *Replicated) CheckDivergence(collection, bucket string) (counts map[string]int, diverged bool, err error) {
+	counts = make(map[string]int, len(r.replicas))
+	for n, replica := range r.replicas {
+		var count int
+		err := r.run(replica, func() error {
+			var err error
+			count, err = replica.Ingest.Count(collection, bucket, "")
+			return err
+		})
+		if err != nil {
+			return nil, false, err
+		}
+		counts[replica.Name] = count
+		if n > 0 && count != counts[r.replicas[0].Name] {
+			diverged = true
+		}
+	}
+	return counts, diverged, nil
+}
+
+// run execute fn on the replica goroutine in asynchronous mode and wait for its result,
+// or directly in synchronous mode. It fails with ErrClosed after Quit.
+func (r *Replicated) run(replica *replicaState, fn func() error) error {
+	r.closeMu.RLock()
+	if r.closed {
+		r.closeMu.RUnlock()
+		return ErrClosed
+	}
+	if replica.queue == nil {
+		r.closeMu.RUnlock()
+		return fn()
+	}
+	done := make(chan error, 1)
+	replica.queue <- func() { done <- fn() }
+	r.closeMu.RUnlock()
+	return <-done
+}
+
+// write mirror a command to every replica.
+// In synchronous mode, the first error is returned once all replicas are done.
+func (r *Replicated) write(d Divergence, fn func(ing Ingestable) error) error {
+	return r.writeReplicas(d, func(replica *replicaState) error {
+		return fn(replica.Ingest)
+	})
+}
+
+func (r *Replicated) writeReplicas(d Divergence, fn func(replica *replicaState) error) error {
+	r.closeMu.RLock()
+	defer r.closeMu.RUnlock()
+	if r.closed {
+		return ErrClosed
+	}
+
+	if r.opts.Mode == ReplicateAsync {
+		for _, replica := range r.replicas {
+			replica := replica
+			task := func() {
+				if err := fn(replica); err != nil {
+					r.diverged(replica, d, err)
+				}
+			}
+			select {
+			case replica.queue <- task:
+			default:
+				r.diverged(replica, d, ErrReplicaQueueFull)
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, len(r.replicas))
+	var wg sync.WaitGroup
+	for n, replica := range r.replicas {
+		wg.Add(1)
+		go func(n int, replica *replicaState) {
+			defer wg.Done()
+			errs[n] = fn(replica)
+		}(n, replica)
+	}
+	wg.Wait()
+
+	var first error
+	for n, err := range errs {
+		if err == nil {
+			continue
+		}
+		r.diverged(r.replicas[n], d, err)
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// writeBulk mirror a bulk command of records, in synchronous mode the errors of the first replica are returned.
+func (r *Replicated) writeBulk(d Divergence, records []IngestBulkRecord, fn func(ing Ingestable) []IngestBulkError) []IngestBulkError {
+	errs := make([]IngestBulkError, 0)
+	err := r.writeReplicas(d, func(replica *replicaState) error {
+		bulkErrs := fn(replica.Ingest)
+		if replica == r.replicas[0] && r.opts.Mode == ReplicateSync {
+			errs = bulkErrs
+		}
+		if len(bulkErrs) > 0 {
+			return bulkErrs[0].Error
+		}
+		return nil
+	})
+	if err == ErrClosed {
+		return bulkErrors(records, err)
+	}
+	return errs
+}
+
+func (r *Replicated) diverged(replica *replicaState, d Divergence, err error) {
+	if r.opts.OnDivergence == nil {
+		return
+	}
+	d.Replica, d.Err = replica.Name, err
+	r.opts.OnDivergence(d)
+}
+
+// read run fn on the current replica, failing over to the next healthy one
+// when it fails and the replica doesn't answer PING.
+func (r *Replicated) read(fn func(s Searchable) error) error {
+	var err error
+	for tries := 0; tries < len(r.replicas); tries++ {
+		replica := r.healthy()
+		if replica == nil {
+			break
+		}
+		err = fn(replica.Search)
+		if err == nil {
+			return nil
+		}
+		if replica.Search.Ping() == nil {
+			// sonic answered, the error comes from the command itself
+			return err
+		}
+		r.markDown(replica)
+	}
+	if err == nil {
+		err = ErrNoReplica
+	}
+	return err
+}
+
+// healthy return the current replica if it is up, or the next one which is.
+func (r *Replicated) healthy() *replicaState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for n := 0; n < len(r.replicas); n++ {
+		i := (r.current + n) % len(r.replicas)
+		if now.After(r.replicas[i].downUntil) {
+			r.current = i
+			return r.replicas[i]
+		}
+	}
+	return nil
+}
+
+func (r *Replicated) markDown(replica *replicaState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	replica.downUntil = time.Now().Add(r.opts.RetryInterval)
+}
+
+func (r *Replicated) Query(collection, bucket, terms string, limit, offset int, lang Lang) (results []string, err error) {
+	err = r.read(func(s Searchable) error {
+		results, err = s.Query(collection, bucket, terms, limit, offset, lang)
+		return err
+	})
+	return results, err
+}
+
+func (r *Replicated) Suggest(collection, bucket, word string, limit int) (results []string, err error) {
+	err = r.read(func(s Searchable) error {
+		results, err = s.Suggest(collection, bucket, word, limit)
+		return err
+	})
+	return results, err
+}
+
+func (r *Replicated) List(collection, bucket string, limit, offset int) (results []string, err error) {
+	err = r.read(func(s Searchable) error {
+		results, err = s.List(collection, bucket, limit, offset)
+		return err
+	})
+	return results, err
+}
+
+// NewQuery build a query executed with failover,
+// it is built again on the query builder of the replica serving it.
+func (r *Replicated) NewQuery(collection, bucket string) *QueryBuilder {
+	q := newQueryBuilder(collection, bucket, nil)
+	q.exec = func(string) (results []string, err error) {
+		err = r.read(func(s Searchable) error {
+			results, err = q.copyTo(s.NewQuery(collection, bucket)).Exec()
+			return err
+		})
+		return results, err
+	}
+	return q
+}
+
+// QueryBuckets fail over to the next replica only when every bucket failed.
+func (r *Replicated) QueryBuckets(collection string, buckets []string, terms string, limit int, lang Lang, parallelRoutines int, strategy MergeStrategy) (results []BucketResult, errs []QueryBucketError) {
+	err := r.read(func(s Searchable) error {
+		results, errs = s.QueryBuckets(collection, buckets, terms, limit, lang, parallelRoutines, strategy)
+		if len(buckets) > 0 && len(errs) == len(buckets) {
+			return errs[0].Error
+		}
+		return nil
+	})
+	if err == ErrNoReplica {
+		errs = make([]QueryBucketError, 0, len(buckets))
+		for _, bucket := range buckets {
+			errs = append(errs, QueryBucketError{bucket, err})
+		}
+	}
+	return results, errs
+}
+
+func (r *Replicated) Push(collection, bucket, object, text string, lang Lang) (err error) {
+	return r.write(Divergence{Command: string(push), Collection: collection, Bucket: bucket, Object: object}, func(ing Ingestable) error {
+		return ing.Push(collection, bucket, object, text, lang)
+	})
+}
+
+func (r *Replicated) BulkPush(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	return r.writeBulk(Divergence{Command: string(push), Collection: collection, Bucket: bucket}, records, func(ing Ingestable) []IngestBulkError {
+		return ing.BulkPush(collection, bucket, parallelRoutines, records, lang)
+	})
+}
+
+func (r *Replicated) Pop(collection, bucket, object, text string) (err error) {
+	return r.write(Divergence{Command: string(pop), Collection: collection, Bucket: bucket, Object: object}, func(ing Ingestable) error {
+		return ing.Pop(collection, bucket, object, text)
+	})
+}
+
+func (r *Replicated) BulkPop(collection, bucket string, parallelRoutines int, records []IngestBulkRecord) []IngestBulkError {
+	return r.writeBulk(Divergence{Command: string(pop), Collection: collection, Bucket: bucket}, records, func(ing Ingestable) []IngestBulkError {
+		return ing.BulkPop(collection, bucket, parallelRoutines, records)
+	})
+}
+
+// Count is read from the first replica answering.
+func (r *Replicated) Count(collection, bucket, object string) (count int, err error) {
+	for _, replica := range r.replicas {
+		err = r.run(replica, func() error {
+			var err error
+			count, err = replica.Ingest.Count(collection, bucket, object)
+			return err
+		})
+		if err == nil {
+			return count, nil
+		}
+	}
+	return 0, err
+}
+
+func (r *Replicated) FlushCollection(collection string) (err error) {
+	return r.write(Divergence{Command: string(flushc), Collection: collection}, func(ing Ingestable) error {
+		return ing.FlushCollection(collection)
+	})
+}
+
+func (r *Replicated) FlushBucket(collection, bucket string) (err error) {
+	return r.write(Divergence{Command: string(flushb), Collection: collection, Bucket: bucket}, func(ing Ingestable) error {
+		return ing.FlushBucket(collection, bucket)
+	})
+}
+
+func (r *Replicated) FlushObject(collection, bucket, object string) (err error) {
+	return r.write(Divergence{Command: string(flusho), Collection: collection, Bucket: bucket, Object: object}, func(ing Ingestable) error {
+		return ing.FlushObject(collection, bucket, object)
+	})
+}
+
+func (r *Replicated) Replace(collection, bucket, object, text string, lang Lang) (err error) {
+	return r.write(Divergence{Command: "REPLACE", Collection: collection, Bucket: bucket, Object: object}, func(ing Ingestable) error {
+		return ing.Replace(collection, bucket, object, text, lang)
+	})
+}
+
+func (r *Replicated) BulkReplace(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	return r.writeBulk(Divergence{Command: "REPLACE", Collection: collection, Bucket: bucket}, records, func(ing Ingestable) []IngestBulkError {
+		return ing.BulkReplace(collection, bucket, parallelRoutines, records, lang)
+	})
+}
+
+func (r *Replicated) Update(collection, bucket, object, oldText, newText string, lang Lang) (err error) {
+	return r.write(Divergence{Command: "UPDATE", Collection: collection, Bucket: bucket, Object: object}, func(ing Ingestable) error {
+		return ing.Update(collection, bucket, object, oldText, newText, lang)
+	})
+}
+
+// Quit wait for the queued commands to be sent then quit every replica, the first error is returned.
+// Calling it again does nothing, the commands sent after it fail with ErrClosed.
+func (r *Replicated) Quit() (err error) {
+	r.closeMu.Lock()
+	if r.closed {
+		r.closeMu.Unlock()
+		return nil
+	}
+	r.closed = true
+	for _, replica := range r.replicas {
+		if replica.queue != nil {
+			close(replica.queue)
+		}
+	}
+	r.closeMu.Unlock()
+	r.wg.Wait()
+
+	for _, replica := range r.replicas {
+		for _, b := range []Base{replica.Search, replica.Ingest} {
+			if b == nil {
+				continue
+			}
+			if e := b.Quit(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Ping ping every replica and update their health, an error is returned only if none answered.
+func (r *Replicated) Ping() (err error) {
+	alive := false
+	for _, replica := range r.replicas {
+		e := replica.Search.Ping()
+		if e == nil {
+			e = r.run(replica, replica.Ingest.Ping)
+		}
+		if e != nil {
+			r.markDown(replica)
+			err = e
+			continue
+		}
+		r.mu.Lock()
+		replica.downUntil = time.Time{}
+		r.mu.Unlock()
+		alive = true
+	}
+	if alive {
+		return nil
+	}
+	return err
+}
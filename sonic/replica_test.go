@@ -0,0 +1,112 @@
+package sonic
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newTestReplica(t *testing.T, name string, results string) (Replica, *fakeServer) {
+	server := newFakeServer(t, 20000, queryReplies(map[string]string{"movies general": results}))
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Replica{Name: name, Search: search, Ingest: ing}, server
+}
+
+func TestReplicated(t *testing.T) {
+	for _, mode := range []ReplicationMode{ReplicateSync, ReplicateAsync} {
+		first, firstServer := newTestReplica(t, "first", "id:1")
+		second, secondServer := newTestReplica(t, "second", "id:2")
+
+		var mu sync.Mutex
+		var divergences []Divergence
+		r, err := NewReplicated([]Replica{first, second}, ReplicatedOptions{
+			Mode: mode,
+			OnDivergence: func(d Divergence) {
+				mu.Lock()
+				divergences = append(divergences, d)
+				mu.Unlock()
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.Push("movies", "general", "id:3", "Spider man", LangNone); err != nil {
+			t.Fatal(err)
+		}
+		if mode == ReplicateAsync {
+			// Ping goes through the queues, so the push is received before the query
+			if err := r.Ping(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if results, err := r.Query("movies", "general", "man", 10, 0, LangNone); err != nil || !reflect.DeepEqual(results, []string{"id:1"}) {
+			t.Errorf("mode %d: expected results of the first replica, got %v %v", mode, results, err)
+		}
+
+		firstServer.close()
+		if results, err := r.Query("movies", "general", "man", 10, 0, LangNone); err != nil || !reflect.DeepEqual(results, []string{"id:2"}) {
+			t.Errorf("mode %d: expected failover to the second replica, got %v %v", mode, results, err)
+		}
+		if status := r.Status(); status["first"] || !status["second"] {
+			t.Errorf("mode %d: unexpected status %v", mode, status)
+		}
+
+		err = r.FlushBucket("movies", "general")
+		if mode == ReplicateSync && err == nil {
+			t.Errorf("mode %d: expected the flush to fail on the first replica", mode)
+		}
+		_ = r.Quit()
+		if err := r.Quit(); err != nil {
+			t.Errorf("mode %d: expected Quit to be idempotent, got %v", mode, err)
+		}
+		if err := r.Push("movies", "general", "id:4", "Batman", LangNone); err != ErrClosed {
+			t.Errorf("mode %d: expected ErrClosed after Quit, got %v", mode, err)
+		}
+		if _, err := r.Count("movies", "general", ""); err != ErrClosed {
+			t.Errorf("mode %d: expected ErrClosed after Quit, got %v", mode, err)
+		}
+
+		mu.Lock()
+		if len(divergences) != 1 || divergences[0].Replica != "first" || divergences[0].Command != "FLUSHB" {
+			t.Errorf("mode %d: unexpected divergences %+v", mode, divergences)
+		}
+		mu.Unlock()
+
+		for _, server := range []*fakeServer{firstServer, secondServer} {
+			received := server.received()
+			if len(received) == 0 || received[0] != `PUSH movies general id:3 "Spider man" LANG(none)` {
+				t.Errorf("mode %d: expected the push to be mirrored, got %v", mode, received)
+			}
+		}
+	}
+}
+
+func TestReplicated_NewQuery(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(map[string]string{"acme--movies general": "id:1"}))
+	acme, err := newTestTenants(t, server, TenantOptions{Collections: []string{"movies"}}).Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReplicated([]Replica{{Name: "acme", Search: acme, Ingest: acme}}, ReplicatedOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the query is built again by the replica, which scope it to the tenant
+	results, err := r.NewQuery("movies", "general").Terms("man").Limit(5).Lang(LangNone).Exec()
+	if err != nil || !reflect.DeepEqual(results, []string{"id:1"}) {
+		t.Errorf("expected the results of the tenant, got %v %v", results, err)
+	}
+	expected := []string{`QUERY acme--movies general "man" LIMIT(5) LANG(none)`}
+	if received := server.received(); !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+}
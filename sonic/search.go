@@ -2,6 +2,7 @@ package sonic
 
 import (
 	"fmt"
+	"runtime"
 	"strings"
 )
 
@@ -17,6 +18,21 @@ type Searchable interface {
 	// Command syntax SUGGEST <collection> <bucket> "<word>" [LIMIT(<count>)]?.
 	Suggest(collection, bucket, word string, limit int) (results []string, err error)
 
+	// List the words of the index, return a list of words as a string.
+	// Command syntax LIST <collection> <bucket> [LIMIT(<count>)]? [OFFSET(<count>)]?.
+	List(collection, bucket string, limit, offset int) (results []string, err error)
+
+	// NewQuery start building a query, only the options which are set are sent.
+	// eg. s.NewQuery("movies", "general").Terms("man").Limit(20).Lang(LangEng).Exec()
+	NewQuery(collection, bucket string) *QueryBuilder
+
+	// QueryBuckets query several buckets of a collection concurrently and merge the results.
+	// Queries are dispatched on N (parallelRoutines) pooled connections, idle ones are kept until Quit.
+	// limit is applied to each bucket and to the merged results.
+	// Failed buckets are reported in errs, the results of the others are still returned.
+	// If parallelRoutines <= 0; parallelRoutines will be equal to 1.
+	QueryBuckets(collection string, buckets []string, terms string, limit int, lang Lang, parallelRoutines int, strategy MergeStrategy) (results []BucketResult, errs []QueryBucketError)
+
 	// Quit refer to the Base interface
 	Quit() (err error)
 
@@ -29,68 +45,63 @@ type searchCommands string
 const (
 	query   searchCommands = "QUERY"
 	suggest searchCommands = "SUGGEST"
+	list    searchCommands = "LIST"
 )
 
 type searchChannel struct {
 	*driver
+	pool *pool
 }
 
 // NewSearch create a new driver instance with a searchChannel instance.
 // Only way to get a Searchable implementation.
-func NewSearch(host string, port int, password string) (Searchable, error) {
-	driver := &driver{
-		Host:     host,
-		Port:     port,
-		Password: password,
-		channel:  Search,
-	}
+func NewSearch(host string, port int, password string, opts ...Option) (Searchable, error) {
+	driver := newDriver(host, port, password, Search, opts)
 	err := driver.Connect()
 	if err != nil {
 		return nil, err
 	}
 	return searchChannel{
 		driver: driver,
+		pool:   newPool(driver, runtime.NumCPU()),
 	}, nil
 }
 
-func (s searchChannel) Query(collection, bucket, term string, limit, offset int, lang Lang) (results []string, err error) {
-	err = s.write(fmt.Sprintf("%s %s %s \"%s\" LIMIT(%d) OFFSET(%d)"+langFormat(lang), query, collection, bucket, term, limit, offset, lang))
-	if err != nil {
-		return nil, err
-	}
-
-	// pending, should be PENDING ID_EVENT
-	_, err = s.read()
-	if err != nil {
-		return nil, err
+// Quit close the pooled connections then the channel.
+func (s searchChannel) Quit() error {
+	if s.pool != nil {
+		s.pool.close()
 	}
+	return s.driver.Quit()
+}
 
-	// event query, should be EVENT QUERY ID_EVENT RESULT1 RESULT2 ...
-	read, err := s.read()
-	if err != nil {
-		return nil, err
-	}
-	return getSearchResults(read, string(query)), nil
+func (s searchChannel) Query(collection, bucket, term string, limit, offset int, lang Lang) (results []string, err error) {
+	return s.search(query, fmt.Sprintf("%s %s %s \"%s\" LIMIT(%d) OFFSET(%d)"+langFormat(lang), query, collection, bucket, term, limit, offset, lang))
 }
 
-func (s searchChannel) Suggest(collection, bucket, word string, limit int) (results []string, err error) {
-	err = s.write(fmt.Sprintf("%s %s %s \"%s\" LIMIT(%d)", suggest, collection, bucket, word, limit))
-	if err != nil {
-		return nil, err
-	}
+func (s searchChannel) NewQuery(collection, bucket string) *QueryBuilder {
+	return newQueryBuilder(collection, bucket, func(cmd string) ([]string, error) {
+		return s.search(query, cmd)
+	})
+}
 
+// search send a search command and wait for its event.
+func (s searchChannel) search(eventType searchCommands, cmd string) (results []string, err error) {
 	// pending, should be PENDING ID_EVENT
-	_, err = s.read()
+	// then event, should be EVENT <eventType> ID_EVENT RESULT1 RESULT2 ...
+	read, err := s.exec(cmd, 2)
 	if err != nil {
 		return nil, err
 	}
+	return getSearchResults(read, string(eventType)), nil
+}
 
-	// event query, should be EVENT SUGGEST ID_EVENT RESULT1 RESULT2 ...
-	read, err := s.read()
-	if err != nil {
-		return nil, err
-	}
-	return getSearchResults(read, string(suggest)), nil
+func (s searchChannel) Suggest(collection, bucket, word string, limit int) (results []string, err error) {
+	return s.search(suggest, fmt.Sprintf("%s %s %s \"%s\" LIMIT(%d)", suggest, collection, bucket, word, limit))
+}
+
+func (s searchChannel) List(collection, bucket string, limit, offset int) (results []string, err error) {
+	return s.search(list, fmt.Sprintf("%s %s %s LIMIT(%d) OFFSET(%d)", list, collection, bucket, limit, offset))
 }
 
 func getSearchResults(line string, eventType string) []string {
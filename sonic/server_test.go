@@ -0,0 +1,125 @@
+package sonic
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer is a minimal sonic server, replying to commands with a handler.
+type fakeServer struct {
+	listener net.Listener
+	port     int
+	buffer   int
+
+	mu       sync.Mutex
+	conns    []net.Conn
+	commands []string
+	handler  func(cmd string) []string
+	password string
+}
+
+// newFakeServer start a server replying with handler to every command except START, PING and QUIT.
+func newFakeServer(t *testing.T, buffer int, handler func(cmd string) []string) *fakeServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{
+		listener: l,
+		port:     l.Addr().(*net.TCPAddr).Port,
+		buffer:   buffer,
+		handler:  handler,
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	_, _ = fmt.Fprintf(conn, "CONNECTED <sonic-server v1.4.0>\r\n")
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		cmd := scanner.Text()
+
+		var replies []string
+		switch {
+		case strings.HasPrefix(cmd, "START "):
+			fields := strings.Fields(cmd)
+			s.mu.Lock()
+			password := s.password
+			s.mu.Unlock()
+			if password != "" && (len(fields) < 3 || fields[2] != password) {
+				_, _ = fmt.Fprintf(conn, "ENDED authentication_failed\r\n")
+				return
+			}
+			replies = []string{fmt.Sprintf("STARTED %s protocol(1) buffer(%d)", fields[1], s.buffer)}
+		case cmd == "PING":
+			replies = []string{"PONG"}
+		case cmd == "QUIT":
+			_, _ = fmt.Fprintf(conn, "ENDED quit\r\n")
+			return
+		default:
+			s.mu.Lock()
+			s.commands = append(s.commands, cmd)
+			s.mu.Unlock()
+			replies = s.handler(cmd)
+		}
+
+		for _, r := range replies {
+			_, _ = fmt.Fprintf(conn, "%s\r\n", r)
+		}
+	}
+}
+
+// close stop the server and close the open connections, as if sonic went down.
+func (s *fakeServer) close() {
+	_ = s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+// setPassword make the server reject the START commands with another password.
+func (s *fakeServer) setPassword(password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.password = password
+}
+
+// dropConnections close the open connections, the server keeps accepting new ones.
+func (s *fakeServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.conns = nil
+}
+
+// received return the commands handled so far.
+func (s *fakeServer) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
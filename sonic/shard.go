@@ -0,0 +1,439 @@
+package sonic
+
+import (
+	"errors"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+var (
+	// ErrNoShard is throw when a sharded client has no shard to route to.
+	ErrNoShard = errors.New("no shard available")
+
+	// ErrShardName is throw when a shard name is unknown or already used.
+	ErrShardName = errors.New("invalid shard name")
+)
+
+// shardReplicas is the number of points of each shard on the hash ring.
+const shardReplicas = 128
+
+// Shard is a sonic server of a Sharded client.
+type Shard struct {
+	Name   string
+	Search Searchable
+	Ingest Ingestable
+}
+
+// ShardKey identify the data routed to a shard.
+type ShardKey struct {
+	Collection, Bucket string
+}
+
+// Migration is a bucket to move from a shard to another.
+// Sonic can't export its index, so the bucket data has to be pushed again
+// to the new shard from the source of truth, then flushed from the old one.
+type Migration struct {
+	ShardKey
+	From, To string
+}
+
+// Sharded route each collection and bucket to one of several sonic servers,
+// either by consistent hashing or by an explicit routing table.
+// Operations spanning a whole collection (FlushCollection, Count without bucket)
+// are sent to every shard. It implements Searchable and Ingestable.
+//
+// Sharded is safe for concurrent use, the channels it wraps serialize their commands.
+type Sharded struct {
+	mu     sync.RWMutex
+	shards map[string]Shard
+	ring   []ringPoint
+	routes map[ShardKey]string
+}
+
+type ringPoint struct {
+	hash  uint32
+	shard string
+}
+
+// NewSharded create a sharded client over the given shards, names must be unique.
+func NewSharded(shards ...Shard) (*Sharded, error) {
+	s := &Sharded{
+		shards: make(map[string]Shard),
+		routes: make(map[ShardKey]string),
+	}
+	for _, shard := range shards {
+		if _, ok := s.shards[shard.Name]; ok || shard.Name == "" {
+			return nil, ErrShardName
+		}
+		s.shards[shard.Name] = shard
+	}
+	s.ring = buildRing(s.shards)
+	return s, nil
+}
+
+// Route pin a bucket to a shard, overriding consistent hashing.
+// An empty bucket pin the whole collection.
+func (s *Sharded) Route(collection, bucket, shard string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.shards[shard]; !ok {
+		return ErrShardName
+	}
+	s.routes[ShardKey{collection, bucket}] = shard
+	return nil
+}
+
+// ShardFor return the shard holding a bucket.
+func (s *Sharded) ShardFor(collection, bucket string) (Shard, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name := s.route(s.ring, collection, bucket)
+	if name == "" {
+		return Shard{}, ErrNoShard
+	}
+	return s.shards[name], nil
+}
+
+// PlanAddShard return the buckets, among keys, which would move if a shard named name was added.
+func (s *Sharded) PlanAddShard(name string, keys []ShardKey) []Migration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shards := make(map[string]Shard, len(s.shards)+1)
+	for n, shard := range s.shards {
+		shards[n] = shard
+	}
+	shards[name] = Shard{Name: name}
+	return s.plan(buildRing(shards), keys)
+}
+
+// AddShard add a shard and return the buckets, among keys, which are now routed to it.
+// The migration of their data is up to the caller, see Migration.
+func (s *Sharded) AddShard(shard Shard, keys []ShardKey) ([]Migration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.shards[shard.Name]; ok || shard.Name == "" {
+		return nil, ErrShardName
+	}
+
+	s.shards[shard.Name] = shard
+	ring := buildRing(s.shards)
+	migrations := s.plan(ring, keys)
+	s.ring = ring
+	return migrations, nil
+}
+
+func (s *Sharded) plan(ring []ringPoint, keys []ShardKey) []Migration {
+	migrations := make([]Migration, 0)
+	for _, k := range keys {
+		from, to := s.route(s.ring, k.Collection, k.Bucket), s.route(ring, k.Collection, k.Bucket)
+		if from != to {
+			migrations = append(migrations, Migration{ShardKey: k, From: from, To: to})
+		}
+	}
+	return migrations
+}
+
+// route return the shard name of a bucket on the given ring, s.mu must be held.
+func (s *Sharded) route(ring []ringPoint, collection, bucket string) string {
+	if name, ok := s.routes[ShardKey{collection, bucket}]; ok {
+		return name
+	}
+	if name, ok := s.routes[ShardKey{collection, ""}]; ok {
+		return name
+	}
+	if len(ring) == 0 {
+		return ""
+	}
+	h := hashKey(collection + "\x00" + bucket)
+	n := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if n == len(ring) {
+		n = 0
+	}
+	return ring[n].shard
+}
+
+// all return every shard, in name order.
+func (s *Sharded) all() []Shard {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	shards := make([]Shard, 0, len(s.shards))
+	for _, shard := range s.shards {
+		shards = append(shards, shard)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i].Name < shards[j].Name })
+	return shards
+}
+
+// collectionShards return the shards which may hold data of a collection:
+// the shard the collection is pinned to, unless some of its buckets are pinned elsewhere.
+func (s *Sharded) collectionShards(collection string) []Shard {
+	s.mu.RLock()
+	name, pinned := s.routes[ShardKey{collection, ""}]
+	for k, n := range s.routes {
+		if k.Collection == collection && n != name {
+			pinned = false
+		}
+	}
+	shard := s.shards[name]
+	s.mu.RUnlock()
+
+	if pinned {
+		return []Shard{shard}
+	}
+	return s.all()
+}
+
+func buildRing(shards map[string]Shard) []ringPoint {
+	ring := make([]ringPoint, 0, len(shards)*shardReplicas)
+	for name := range shards {
+		for i := 0; i < shardReplicas; i++ {
+			ring = append(ring, ringPoint{hashKey(name + "#" + strconv.Itoa(i)), name})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		if ring[i].hash == ring[j].hash {
+			return ring[i].shard < ring[j].shard
+		}
+		return ring[i].hash < ring[j].hash
+	})
+	return ring
+}
+
+func hashKey(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32()
+}
+
+func (s *Sharded) Query(collection, bucket, terms string, limit, offset int, lang Lang) (results []string, err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return shard.Search.Query(collection, bucket, terms, limit, offset, lang)
+}
+
+func (s *Sharded) Suggest(collection, bucket, word string, limit int) (results []string, err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return shard.Search.Suggest(collection, bucket, word, limit)
+}
+
+func (s *Sharded) List(collection, bucket string, limit, offset int) (results []string, err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return shard.Search.List(collection, bucket, limit, offset)
+}
+
+func (s *Sharded) NewQuery(collection, bucket string) *QueryBuilder {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return newQueryBuilder(collection, bucket, func(string) ([]string, error) {
+			return nil, err
+		})
+	}
+	return shard.Search.NewQuery(collection, bucket)
+}
+
+// QueryBuckets group the buckets by shard, query the shards concurrently then merge the results.
+func (s *Sharded) QueryBuckets(collection string, buckets []string, terms string, limit int, lang Lang, parallelRoutines int, strategy MergeStrategy) (results []BucketResult, errs []QueryBucketError) {
+	byShard := make(map[string][]string)
+	var shards []Shard
+	errs = make([]QueryBucketError, 0)
+	for _, bucket := range buckets {
+		shard, err := s.ShardFor(collection, bucket)
+		if err != nil {
+			errs = append(errs, QueryBucketError{bucket, err})
+			continue
+		}
+		if _, ok := byShard[shard.Name]; !ok {
+			shards = append(shards, shard)
+		}
+		byShard[shard.Name] = append(byShard[shard.Name], bucket)
+	}
+
+	perBucket := make(map[string][]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, shard := range shards {
+		wg.Add(1)
+		go func(shard Shard, shardBuckets []string) {
+			defer wg.Done()
+			// priority merge with a limit large enough to get every bucket results back
+			shardLimit := limit
+			if limit > 0 {
+				shardLimit = limit * len(shardBuckets)
+			}
+			res, shardErrs := shard.Search.QueryBuckets(collection, shardBuckets, terms, shardLimit, lang, parallelRoutines, MergePriority)
+
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, shardErrs...)
+			for _, r := range res {
+				if limit <= 0 || len(perBucket[r.Bucket]) < limit {
+					perBucket[r.Bucket] = append(perBucket[r.Bucket], r.Object)
+				}
+			}
+		}(shard, byShard[shard.Name])
+	}
+	wg.Wait()
+
+	ordered := make([][]string, len(buckets))
+	for n, bucket := range buckets {
+		ordered[n] = perBucket[bucket]
+	}
+	results = mergeBucketResults(buckets, ordered, strategy)
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, errs
+}
+
+func (s *Sharded) Push(collection, bucket, object, text string, lang Lang) (err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return shard.Ingest.Push(collection, bucket, object, text, lang)
+}
+
+func (s *Sharded) BulkPush(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return bulkErrors(records, err)
+	}
+	return shard.Ingest.BulkPush(collection, bucket, parallelRoutines, records, lang)
+}
+
+func (s *Sharded) Pop(collection, bucket, object, text string) (err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return shard.Ingest.Pop(collection, bucket, object, text)
+}
+
+func (s *Sharded) BulkPop(collection, bucket string, parallelRoutines int, records []IngestBulkRecord) []IngestBulkError {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return bulkErrors(records, err)
+	}
+	return shard.Ingest.BulkPop(collection, bucket, parallelRoutines, records)
+}
+
+// Count sum the counts of every shard holding the collection when bucket is empty.
+func (s *Sharded) Count(collection, bucket, object string) (count int, err error) {
+	if bucket != "" {
+		shard, err := s.ShardFor(collection, bucket)
+		if err != nil {
+			return 0, err
+		}
+		return shard.Ingest.Count(collection, bucket, object)
+	}
+
+	for _, shard := range s.collectionShards(collection) {
+		n, err := shard.Ingest.Count(collection, bucket, object)
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// FlushCollection flush the collection on every shard holding it.
+func (s *Sharded) FlushCollection(collection string) (err error) {
+	for _, shard := range s.collectionShards(collection) {
+		e := shard.Ingest.FlushCollection(collection)
+		if e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (s *Sharded) FlushBucket(collection, bucket string) (err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return shard.Ingest.FlushBucket(collection, bucket)
+}
+
+func (s *Sharded) FlushObject(collection, bucket, object string) (err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return shard.Ingest.FlushObject(collection, bucket, object)
+}
+
+func (s *Sharded) Replace(collection, bucket, object, text string, lang Lang) (err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return shard.Ingest.Replace(collection, bucket, object, text, lang)
+}
+
+func (s *Sharded) BulkReplace(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return bulkErrors(records, err)
+	}
+	return shard.Ingest.BulkReplace(collection, bucket, parallelRoutines, records, lang)
+}
+
+func (s *Sharded) Update(collection, bucket, object, oldText, newText string, lang Lang) (err error) {
+	shard, err := s.ShardFor(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return shard.Ingest.Update(collection, bucket, object, oldText, newText, lang)
+}
+
+// Quit quit the channels of every shard, the first error is returned.
+func (s *Sharded) Quit() (err error) {
+	for _, shard := range s.all() {
+		for _, b := range []Base{shard.Search, shard.Ingest} {
+			if b == nil {
+				continue
+			}
+			if e := b.Quit(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// Ping ping the channels of every shard, the first error is returned.
+func (s *Sharded) Ping() (err error) {
+	for _, shard := range s.all() {
+		for _, b := range []Base{shard.Search, shard.Ingest} {
+			if b == nil {
+				continue
+			}
+			if e := b.Ping(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// bulkErrors report every record with the same error.
+func bulkErrors(records []IngestBulkRecord, err error) []IngestBulkError {
+	errs := make([]IngestBulkError, 0, len(records))
+	for _, rec := range records {
+		addBulkError(&errs, rec, err)
+	}
+	return errs
+}
@@ -0,0 +1,112 @@
+package sonic
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSharded_Routing(t *testing.T) {
+	s, err := NewSharded(Shard{Name: "a"}, Shard{Name: "b"}, Shard{Name: "c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []ShardKey
+	counts := make(map[string]int)
+	for n := 0; n < 3000; n++ {
+		k := ShardKey{"messages", fmt.Sprintf("user-%d", n)}
+		keys = append(keys, k)
+		shard, err := s.ShardFor(k.Collection, k.Bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[shard.Name]++
+	}
+	for name, n := range counts {
+		if n < 500 {
+			t.Errorf("shard %s only got %d buckets out of 3000", name, n)
+		}
+	}
+
+	plan := s.PlanAddShard("d", keys)
+	migrations, err := s.AddShard(Shard{Name: "d"}, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != len(migrations) || len(migrations) == 0 || len(migrations) > 1200 {
+		t.Errorf("unexpected number of migrations: planned %d, got %d", len(plan), len(migrations))
+	}
+	for _, m := range migrations {
+		if m.To != "d" {
+			t.Errorf("bucket %s moved from %s to %s instead of the new shard", m.Bucket, m.From, m.To)
+		}
+	}
+
+	if err := s.Route("messages", "user-1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Route("archives", "", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if shard, _ := s.ShardFor("messages", "user-1"); shard.Name != "a" {
+		t.Errorf("expected explicit route to a, got %s", shard.Name)
+	}
+	if shard, _ := s.ShardFor("archives", "any"); shard.Name != "b" {
+		t.Errorf("expected collection route to b, got %s", shard.Name)
+	}
+	if err := s.Route("archives", "", "unknown"); err != ErrShardName {
+		t.Errorf("expected ErrShardName, got %v", err)
+	}
+}
+
+func TestSharded_FanOut(t *testing.T) {
+	var shards []Shard
+	var servers []*fakeServer
+	for n := 0; n < 2; n++ {
+		count := n + 1
+		server := newFakeServer(t, 20000, func(cmd string) []string {
+			if strings.HasPrefix(cmd, "COUNT ") {
+				return []string{fmt.Sprintf("RESULT %d", count)}
+			}
+			return []string{"OK"}
+		})
+		ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword")
+		if err != nil {
+			t.Fatal(err)
+		}
+		shards = append(shards, Shard{Name: fmt.Sprint(n), Ingest: ing})
+		servers = append(servers, server)
+	}
+	s, err := NewSharded(shards...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Quit()
+
+	count, err := s.Count("messages", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected the counts of both shards to be summed, got %d", count)
+	}
+
+	if err := s.FlushCollection("messages"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Push("messages", "user-1", "id:1", "hello", LangNone); err != nil {
+		t.Fatal(err)
+	}
+	pushes := 0
+	for _, server := range servers {
+		received := server.received()
+		if received[1] != "FLUSHC messages" {
+			t.Errorf("expected FLUSHC to be sent to every shard, got %v", received)
+		}
+		pushes += len(received) - 2
+	}
+	if pushes != 1 {
+		t.Errorf("expected the push to be routed to a single shard, got %d", pushes)
+	}
+}
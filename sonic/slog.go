@@ -0,0 +1,36 @@
+//go:build go1.21
+
+package sonic
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogHook log every command with logger, at level or at error level when it fails.
+// It is only built with Go 1.21 or later, which has log/slog.
+func SlogHook(logger *slog.Logger, level slog.Level) Hook {
+	return func(CommandEvent) func(CommandEvent) {
+		return func(e CommandEvent) {
+			lvl := level
+			attrs := []slog.Attr{
+				slog.String("channel", string(e.Channel)),
+				slog.String("command", e.Command),
+				slog.Duration("duration", e.Duration),
+				slog.Int("bytes_sent", e.BytesSent),
+				slog.Int("bytes_received", e.BytesReceived),
+			}
+			if e.Collection != "" {
+				attrs = append(attrs, slog.String("collection", e.Collection))
+			}
+			if e.Bucket != "" {
+				attrs = append(attrs, slog.String("bucket", e.Bucket))
+			}
+			if e.Err != nil {
+				lvl = slog.LevelError
+				attrs = append(attrs, slog.String("error", e.Err.Error()))
+			}
+			logger.LogAttrs(context.Background(), lvl, "sonic command", attrs...)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+//go:build go1.21
+
+package sonic
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogHook(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		if strings.HasPrefix(cmd, "FLUSHB") {
+			return []string{"ERR not found"}
+		}
+		return []string{"OK"}
+	})
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword", WithHooks(SlogHook(logger, slog.LevelInfo)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ing.Quit()
+	_ = ing.Push("movies", "general", "id:1", "Spider man", LangNone)
+	_ = ing.FlushBucket("movies", "general")
+
+	if !strings.Contains(logs.String(), "level=INFO msg=\"sonic command\" channel=ingest command=PUSH") ||
+		!strings.Contains(logs.String(), "level=ERROR") {
+		t.Errorf("unexpected logs %q", logs.String())
+	}
+}
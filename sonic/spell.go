@@ -0,0 +1,152 @@
+package sonic
+
+import (
+	"strings"
+)
+
+// CorrectionOptions configure DidYouMean.
+type CorrectionOptions struct {
+	// MaxDistance is the maximum edit distance between a term and its correction, default to 2.
+	MaxDistance int
+
+	// SuggestLimit is the number of words asked to SUGGEST for each prefix, default to 10.
+	SuggestLimit int
+
+	// VocabularyLimit enable the ranking of the bucket vocabulary, fetched with LIST,
+	// in addition to the suggestions. It is the number of words listed, 0 disable it.
+	VocabularyLimit int
+
+	// Requery run the query again with the corrected terms.
+	Requery bool
+
+	// Limit and Lang are used for the queries.
+	Limit int
+	Lang  Lang
+}
+
+// Correction is the result of DidYouMean.
+type Correction struct {
+	// Terms are the corrected terms, or the original ones if nothing was corrected.
+	Terms string
+
+	// Corrected is true when at least one term was corrected.
+	Corrected bool
+
+	// Results of the original query, or of the corrected one with Requery.
+	Results []string
+}
+
+// DidYouMean query the terms and, if there is no result, propose a correction of each term.
+// SUGGEST is asked for completions of shorter and shorter prefixes of the term
+// until one is close enough, optionally the vocabulary of the bucket is also considered.
+// The candidate with the smallest edit distance wins, ties keep the sonic order.
+func DidYouMean(s Searchable, collection, bucket, terms string, opts CorrectionOptions) (*Correction, error) {
+	if opts.MaxDistance <= 0 {
+		opts.MaxDistance = 2
+	}
+	if opts.SuggestLimit <= 0 {
+		opts.SuggestLimit = 10
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+
+	results, err := s.Query(collection, bucket, terms, opts.Limit, 0, opts.Lang)
+	if err != nil {
+		return nil, err
+	}
+	correction := &Correction{Terms: terms, Results: results}
+	if len(results) > 0 {
+		return correction, nil
+	}
+
+	var vocabulary []string
+	if opts.VocabularyLimit > 0 {
+		vocabulary, err = s.List(collection, bucket, opts.VocabularyLimit, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	words := strings.Fields(terms)
+	for n, word := range words {
+		corrected, err := correctWord(s, collection, bucket, word, vocabulary, opts)
+		if err != nil {
+			return nil, err
+		}
+		if corrected != word {
+			words[n] = corrected
+			correction.Corrected = true
+		}
+	}
+	if !correction.Corrected {
+		return correction, nil
+	}
+
+	correction.Terms = strings.Join(words, " ")
+	if opts.Requery {
+		correction.Results, err = s.Query(collection, bucket, correction.Terms, opts.Limit, 0, opts.Lang)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return correction, nil
+}
+
+// correctWord return the closest known word, or word itself if none is within MaxDistance.
+func correctWord(s Searchable, collection, bucket, word string, vocabulary []string, opts CorrectionOptions) (string, error) {
+	lower := strings.ToLower(word)
+	best, bestDistance := word, opts.MaxDistance+1
+	consider := func(candidates []string) {
+		for _, c := range candidates {
+			if d := editDistance(lower, c); d < bestDistance {
+				best, bestDistance = c, d
+			}
+		}
+	}
+
+	consider(vocabulary)
+
+	// the typo is likely at the end of the word, try shorter prefixes until a close word is found
+	runes := []rune(lower)
+	for end := len(runes); end >= 2 && end >= len(runes)-opts.MaxDistance && bestDistance > opts.MaxDistance; end-- {
+		suggestions, err := s.Suggest(collection, bucket, string(runes[:end]), opts.SuggestLimit)
+		if err != nil {
+			return "", err
+		}
+		consider(suggestions)
+	}
+
+	if bestDistance == 0 {
+		return word, nil
+	}
+	return best, nil
+}
+
+// editDistance compute the Levenshtein distance between two strings, counting runes.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,64 @@
+package sonic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"batman", "batman", 0},
+		{"batman", "batmna", 2},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDidYouMean(t *testing.T) {
+	words := []string{"spider", "spiderman", "batman", "superman"}
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		fields := strings.Fields(cmd)
+		switch fields[0] {
+		case "QUERY":
+			if strings.Contains(cmd, `"spider batman"`) {
+				return []string{"PENDING q1", "EVENT QUERY q1 id:1"}
+			}
+			return []string{"PENDING q1", "EVENT QUERY q1"}
+		case "SUGGEST":
+			prefix := strings.Trim(fields[3], `"`)
+			line := "EVENT SUGGEST q1"
+			for _, w := range words {
+				if strings.HasPrefix(w, prefix) {
+					line += " " + w
+				}
+			}
+			return []string{"PENDING q1", line}
+		}
+		return []string{"ERR unexpected"}
+	})
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer search.Quit()
+
+	c, err := DidYouMean(search, "movies", "general", "spidr batmna", CorrectionOptions{Requery: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Correction{Terms: "spider batman", Corrected: true, Results: []string{"id:1"}}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
@@ -0,0 +1,403 @@
+package sonic
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrTenantName is throw when a tenant name is empty, contains spaces or the TenantSeparator,
+	// or starts or ends with a character of the separator.
+	ErrTenantName = errors.New("invalid tenant name")
+
+	// ErrTenantCollection is throw when a tenant use a collection which isn't declared in TenantOptions.
+	ErrTenantCollection = errors.New("collection isn't declared for tenants")
+
+	// ErrTenantBucket is throw when a tenant use a bucket which isn't declared in TenantOptions.
+	ErrTenantBucket = errors.New("bucket isn't declared for tenants")
+
+	// ErrTenantAccess is throw by the Tenants.Guard interceptor when a command isn't scoped to a tenant.
+	ErrTenantAccess = errors.New("command isn't scoped to a tenant")
+)
+
+// TenantSeparator separate the tenant name from the collection or bucket name.
+// It differs from FieldSeparator, so the collections of a FieldIndex can be used by tenants,
+// eg. acme--movies__title.
+const TenantSeparator = "--"
+
+// TenantMode define what is prefixed with the tenant name.
+type TenantMode int
+
+const (
+	// TenantPrefixCollection prefix the collections, eg. movies is acme--movies for the tenant acme.
+	TenantPrefixCollection TenantMode = iota
+
+	// TenantPrefixBucket prefix the buckets, eg. general is acme--general for the tenant acme.
+	// The tenants share the collections.
+	TenantPrefixBucket
+)
+
+// TenantOptions configure Tenants.
+type TenantOptions struct {
+	// Mode define what is prefixed, TenantPrefixCollection by default.
+	Mode TenantMode
+
+	// Collections are the collections the tenants can use, the others are refused.
+	Collections []string
+
+	// Buckets are the buckets the tenants can use, the others are refused.
+	// When empty, any bucket is allowed in TenantPrefixCollection mode.
+	// They are required in TenantPrefixBucket mode, so a collection of a tenant can be flushed
+	// without touching the others.
+	Buckets []string
+}
+
+// Tenants host several customers on one sonic, each one having its own view of the index.
+// Use Tenant to get the view of a customer, it can't reach the data of the others.
+//
+//	tenants, err := sonic.NewTenants(search, ingester, sonic.TenantOptions{Collections: []string{"movies"}})
+//	acme, err := tenants.Tenant("acme")
+//	_ = acme.Push("movies", "general", "id:1", "Spider man", sonic.LangNone) // PUSH acme--movies general ...
+type Tenants struct {
+	search Searchable
+	ingest Ingestable
+	opts   TenantOptions
+
+	collections map[string]bool
+	buckets     map[string]bool
+}
+
+// NewTenants create tenant views over the given channels, one of them can be nil.
+func NewTenants(search Searchable, ingest Ingestable, opts TenantOptions) (*Tenants, error) {
+	if len(opts.Collections) == 0 {
+		return nil, ErrTenantCollection
+	}
+	if opts.Mode == TenantPrefixBucket && len(opts.Buckets) == 0 {
+		return nil, ErrTenantBucket
+	}
+
+	t := &Tenants{
+		search:      search,
+		ingest:      ingest,
+		opts:        opts,
+		collections: make(map[string]bool, len(opts.Collections)),
+		buckets:     make(map[string]bool, len(opts.Buckets)),
+	}
+	for _, c := range opts.Collections {
+		t.collections[c] = true
+	}
+	for _, b := range opts.Buckets {
+		t.buckets[b] = true
+	}
+	return t, nil
+}
+
+// Tenant return the view of a tenant. It implements Searchable and Ingestable.
+func (t *Tenants) Tenant(name string) (*Tenant, error) {
+	if !validTenantName(name) {
+		return nil, ErrTenantName
+	}
+	return &Tenant{tenants: t, name: name}, nil
+}
+
+// validTenantName report whether name can prefix a collection or bucket without ambiguity:
+// the first TenantSeparator of a scoped name is the one following the tenant name.
+func validTenantName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, " \t\r\n\"") && !strings.Contains(name, TenantSeparator) &&
+		strings.Trim(name, TenantSeparator) == name
+}
+
+// Guard return an interceptor refusing the commands which aren't scoped to a tenant,
+// give it to the channels used by Tenants so the index can't be reached without a tenant by mistake.
+func (t *Tenants) Guard() Interceptor {
+	return func(cmd Command, next Invoker) (string, error) {
+		if cmd.Collection == "" {
+			return next(cmd)
+		}
+		if t.opts.Mode == TenantPrefixBucket {
+			if !t.collections[cmd.Collection] || !t.scoped(cmd.Bucket, t.buckets) {
+				return "", ErrTenantAccess
+			}
+		} else if !t.scoped(cmd.Collection, t.collections) || (len(t.buckets) > 0 && cmd.Bucket != "" && !t.buckets[cmd.Bucket]) {
+			return "", ErrTenantAccess
+		}
+		return next(cmd)
+	}
+}
+
+// scoped report whether name is a declared name prefixed by a tenant.
+func (t *Tenants) scoped(name string, declared map[string]bool) bool {
+	n := strings.Index(name, TenantSeparator)
+	return n > 0 && validTenantName(name[:n]) && declared[name[n+len(TenantSeparator):]]
+}
+
+// Quit quit the channels shared by the tenants, the first error is returned.
+func (t *Tenants) Quit() (err error) {
+	for _, b := range []Base{t.search, t.ingest} {
+		if b == nil {
+			continue
+		}
+		if e := b.Quit(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Tenant is the view of the index of a tenant, see Tenants.
+// The collections and buckets it is given are mapped to the ones of the tenant,
+// results are mapped back.
+type Tenant struct {
+	tenants *Tenants
+	name    string
+}
+
+// Name return the name of the tenant.
+func (t *Tenant) Name() string {
+	return t.name
+}
+
+// scope map a collection and bucket to the ones of the tenant.
+func (t *Tenant) scope(collection, bucket string) (string, string, error) {
+	if !t.tenants.collections[collection] {
+		return "", "", ErrTenantCollection
+	}
+	if bucket != "" && len(t.tenants.buckets) > 0 && !t.tenants.buckets[bucket] {
+		return "", "", ErrTenantBucket
+	}
+
+	prefix := t.name + TenantSeparator
+	if t.tenants.opts.Mode == TenantPrefixBucket {
+		if bucket == "" {
+			return collection, "", nil
+		}
+		return collection, prefix + bucket, nil
+	}
+	return prefix + collection, bucket, nil
+}
+
+// unscope map a bucket of the tenant back to the name it was given.
+func (t *Tenant) unscope(bucket string) string {
+	if t.tenants.opts.Mode == TenantPrefixBucket {
+		return strings.TrimPrefix(bucket, t.name+TenantSeparator)
+	}
+	return bucket
+}
+
+func (t *Tenant) Query(collection, bucket, terms string, limit, offset int, lang Lang) (results []string, err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return t.tenants.search.Query(c, b, terms, limit, offset, lang)
+}
+
+func (t *Tenant) Suggest(collection, bucket, word string, limit int) (results []string, err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return t.tenants.search.Suggest(c, b, word, limit)
+}
+
+func (t *Tenant) List(collection, bucket string, limit, offset int) (results []string, err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return t.tenants.search.List(c, b, limit, offset)
+}
+
+func (t *Tenant) NewQuery(collection, bucket string) *QueryBuilder {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return newQueryBuilder(collection, bucket, func(string) ([]string, error) {
+			return nil, err
+		})
+	}
+	return t.tenants.search.NewQuery(c, b)
+}
+
+func (t *Tenant) QueryBuckets(collection string, buckets []string, terms string, limit int, lang Lang, parallelRoutines int, strategy MergeStrategy) (results []BucketResult, errs []QueryBucketError) {
+	errs = make([]QueryBucketError, 0)
+	var c string
+	scoped := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		sc, sb, err := t.scope(collection, bucket)
+		if err != nil {
+			errs = append(errs, QueryBucketError{bucket, err})
+			continue
+		}
+		c = sc
+		scoped = append(scoped, sb)
+	}
+	if len(scoped) == 0 {
+		return nil, errs
+	}
+
+	results, scopedErrs := t.tenants.search.QueryBuckets(c, scoped, terms, limit, lang, parallelRoutines, strategy)
+	for n := range results {
+		results[n].Bucket = t.unscope(results[n].Bucket)
+	}
+	for _, e := range scopedErrs {
+		errs = append(errs, QueryBucketError{t.unscope(e.Bucket), e.Error})
+	}
+	return results, errs
+}
+
+func (t *Tenant) Push(collection, bucket, object, text string, lang Lang) (err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return t.tenants.ingest.Push(c, b, object, text, lang)
+}
+
+func (t *Tenant) BulkPush(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return bulkErrors(records, err)
+	}
+	return t.tenants.ingest.BulkPush(c, b, parallelRoutines, records, lang)
+}
+
+func (t *Tenant) Pop(collection, bucket, object, text string) (err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return t.tenants.ingest.Pop(c, b, object, text)
+}
+
+func (t *Tenant) BulkPop(collection, bucket string, parallelRoutines int, records []IngestBulkRecord) []IngestBulkError {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return bulkErrors(records, err)
+	}
+	return t.tenants.ingest.BulkPop(c, b, parallelRoutines, records)
+}
+
+func (t *Tenant) Replace(collection, bucket, object, text string, lang Lang) (err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return t.tenants.ingest.Replace(c, b, object, text, lang)
+}
+
+func (t *Tenant) BulkReplace(collection, bucket string, parallelRoutines int, records []IngestBulkRecord, lang Lang) []IngestBulkError {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return bulkErrors(records, err)
+	}
+	return t.tenants.ingest.BulkReplace(c, b, parallelRoutines, records, lang)
+}
+
+func (t *Tenant) Update(collection, bucket, object, oldText, newText string, lang Lang) (err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return t.tenants.ingest.Update(c, b, object, oldText, newText, lang)
+}
+
+// Count without bucket return the number of buckets of the tenant in the collection.
+func (t *Tenant) Count(collection, bucket, object string) (count int, err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return 0, err
+	}
+	if bucket != "" || t.tenants.opts.Mode == TenantPrefixCollection {
+		return t.tenants.ingest.Count(c, b, object)
+	}
+
+	// the collection is shared, count the declared buckets holding data
+	for _, bucket := range t.tenants.opts.Buckets {
+		_, b, _ := t.scope(collection, bucket)
+		n, err := t.tenants.ingest.Count(c, b, "")
+		if err != nil {
+			return 0, err
+		}
+		if n > 0 {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// FlushCollection flush the data of the tenant in the collection.
+// When the tenants share the collections, each declared bucket is flushed.
+func (t *Tenant) FlushCollection(collection string) (err error) {
+	c, _, err := t.scope(collection, "")
+	if err != nil {
+		return err
+	}
+	if t.tenants.opts.Mode == TenantPrefixCollection {
+		return t.tenants.ingest.FlushCollection(c)
+	}
+
+	for _, bucket := range t.tenants.opts.Buckets {
+		_, b, _ := t.scope(collection, bucket)
+		if e := t.tenants.ingest.FlushBucket(c, b); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func (t *Tenant) FlushBucket(collection, bucket string) (err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return t.tenants.ingest.FlushBucket(c, b)
+}
+
+func (t *Tenant) FlushObject(collection, bucket, object string) (err error) {
+	c, b, err := t.scope(collection, bucket)
+	if err != nil {
+		return err
+	}
+	return t.tenants.ingest.FlushObject(c, b, object)
+}
+
+// CountBuckets return the number of buckets of the tenant in all the declared collections.
+func (t *Tenant) CountBuckets() (count int, err error) {
+	for _, collection := range t.tenants.opts.Collections {
+		n, err := t.Count(collection, "", "")
+		if err != nil {
+			return 0, err
+		}
+		count += n
+	}
+	return count, nil
+}
+
+// FlushAll flush all the data of the tenant, eg. when the customer leaves.
+// Every declared collection is flushed even if one fails, the first error is returned.
+func (t *Tenant) FlushAll() (err error) {
+	for _, collection := range t.tenants.opts.Collections {
+		if e := t.FlushCollection(collection); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Quit does nothing, the channels are shared by the tenants, see Tenants.Quit.
+func (t *Tenant) Quit() error {
+	return nil
+}
+
+// Ping ping the channels shared by the tenants, the first error is returned.
+func (t *Tenant) Ping() (err error) {
+	for _, b := range []Base{t.tenants.search, t.tenants.ingest} {
+		if b == nil {
+			continue
+		}
+		if e := b.Ping(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
@@ -0,0 +1,147 @@
+package sonic
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTenantPrefixCollection(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(map[string]string{"acme--movies general": "id:1"}))
+	tenants := newTestTenants(t, server, TenantOptions{Collections: []string{"movies"}})
+
+	acme, err := tenants.Tenant("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := acme.Query("movies", "general", "man", 10, 0, LangNone)
+	if err != nil || !reflect.DeepEqual(results, []string{"id:1"}) {
+		t.Errorf("expected the results of the tenant, got %v %v", results, err)
+	}
+	if err := acme.Push("movies", "general", "id:2", "Batman", LangNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := acme.Push("books", "general", "id:2", "Batman", LangNone); err != ErrTenantCollection {
+		t.Errorf("expected an undeclared collection to be refused, got %v", err)
+	}
+	if err := acme.FlushAll(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"acme--corp", "acme-", "-acme", "acme corp"} {
+		if _, err := tenants.Tenant(name); err != ErrTenantName {
+			t.Errorf("expected %q to be an invalid tenant name, got %v", name, err)
+		}
+	}
+
+	expected := []string{
+		`QUERY acme--movies general "man" LIMIT(10) OFFSET(0) LANG(none)`,
+		`PUSH acme--movies general id:2 "Batman" LANG(none)`,
+		"FLUSHC acme--movies",
+	}
+	if received := server.received(); !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+}
+
+func TestTenantPrefixBucket(t *testing.T) {
+	server := newFakeServer(t, 20000, func(cmd string) []string {
+		if strings.HasPrefix(cmd, "COUNT") {
+			if strings.Contains(cmd, "acme--general") {
+				return []string{"RESULT 3"}
+			}
+			return []string{"RESULT 0"}
+		}
+		return queryReplies(map[string]string{"movies acme--general": "id:1", "movies acme--kids": "id:2"})(cmd)
+	})
+	opts := TenantOptions{Mode: TenantPrefixBucket, Collections: []string{"movies"}, Buckets: []string{"general", "kids"}}
+	if _, err := NewTenants(nil, nil, TenantOptions{Mode: TenantPrefixBucket, Collections: opts.Collections}); err != ErrTenantBucket {
+		t.Errorf("expected the buckets to be required, got %v", err)
+	}
+	tenants := newTestTenants(t, server, opts)
+	acme, _ := tenants.Tenant("acme")
+
+	results, errs := acme.QueryBuckets("movies", []string{"general", "kids", "other"}, "man", 10, LangNone, 2, MergePriority)
+	expected := []BucketResult{{"general", "id:1"}, {"kids", "id:2"}}
+	if !reflect.DeepEqual(results, expected) || len(errs) != 1 || errs[0].Bucket != "other" || errs[0].Error != ErrTenantBucket {
+		t.Errorf("unexpected results %v %v", results, errs)
+	}
+	if n, err := acme.CountBuckets(); err != nil || n != 1 {
+		t.Errorf("expected 1 bucket, got %d %v", n, err)
+	}
+	if err := acme.FlushCollection("movies"); err != nil {
+		t.Fatal(err)
+	}
+
+	received := server.received()
+	flushes := received[len(received)-2:]
+	if !reflect.DeepEqual(flushes, []string{"FLUSHB movies acme--general", "FLUSHB movies acme--kids"}) {
+		t.Errorf("expected the buckets of the tenant to be flushed, got %v", flushes)
+	}
+}
+
+func TestTenantsGuard(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(nil))
+	tenants := newTestTenants(t, server, TenantOptions{Collections: []string{"movies"}})
+	ing := tenants.ingest
+
+	for _, cmd := range []func() error{
+		func() error { return ing.FlushCollection("movies") },
+		func() error { return ing.Push("movies", "general", "id:1", "Batman", LangNone) },
+		func() error { return ing.FlushCollection("acme--books") },
+	} {
+		if err := cmd(); err != ErrTenantAccess {
+			t.Errorf("expected the command to be refused, got %v", err)
+		}
+	}
+	if err := ing.Ping(); err != nil {
+		t.Errorf("expected the commands without collection to be allowed, got %v", err)
+	}
+	if received := server.received(); len(received) != 0 {
+		t.Errorf("expected no command to be sent, got %v", received)
+	}
+}
+
+func TestTenantsGuard_FieldCollections(t *testing.T) {
+	server := newFakeServer(t, 20000, queryReplies(nil))
+	title := NewFieldIndex("movies", nil, nil).FieldCollection("title")
+	tenants := newTestTenants(t, server, TenantOptions{Collections: []string{title}})
+
+	// the separators differ, so the tenant of a field collection isn't ambiguous
+	acme, err := tenants.Tenant("acme_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := acme.Push(title, "general", "id:1", "Batman", LangNone); err != nil {
+		t.Errorf("expected the field collection of the tenant to be allowed, got %v", err)
+	}
+	if err := tenants.ingest.FlushCollection("movies__title"); err != ErrTenantAccess {
+		t.Errorf("expected the unscoped field collection to be refused, got %v", err)
+	}
+	expected := []string{`PUSH acme_--movies__title general id:1 "Batman" LANG(none)`}
+	if received := server.received(); !reflect.DeepEqual(received, expected) {
+		t.Errorf("expected %v, got %v", expected, received)
+	}
+}
+
+func newTestTenants(t *testing.T, server *fakeServer, opts TenantOptions) *Tenants {
+	// the guard is created before the channels, so it is given through a late bound interceptor
+	var guard Interceptor
+	intercept := WithInterceptors(func(cmd Command, next Invoker) (string, error) {
+		return guard(cmd, next)
+	})
+	search, err := NewSearch("127.0.0.1", server.port, "SecretPassword", intercept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ing, err := NewIngester("127.0.0.1", server.port, "SecretPassword", intercept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenants, err := NewTenants(search, ing, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard = tenants.Guard()
+	t.Cleanup(func() { _ = tenants.Quit() })
+	return tenants
+}
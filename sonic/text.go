@@ -0,0 +1,136 @@
+package sonic
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var textEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	"\n", "\\n",
+	"\"", "\\\"",
+)
+
+// escapeText escape the characters that can't be sent as is between quotes.
+func escapeText(text string) string {
+	return textEscaper.Replace(text)
+}
+
+// splitText split an escaped text into chunks of at most maxLen bytes.
+// Chunks are cut after a whitespace or a punctuation so words stay whole,
+// a word longer than maxLen is cut on a rune start.
+// A chunk never ends in the middle of an escape sequence.
+// If maxLen <= 0 the text isn't split.
+//
+// Chunks are slices of the original string, concatenated they give back the text.
+func splitText(text string, maxLen int) []string {
+	if maxLen <= 0 || len(text) <= maxLen {
+		return []string{text}
+	}
+
+	var splits []string
+	l := 0
+	for len(text)-l > maxLen {
+		r := splitPoint(text, l, l+maxLen)
+		splits = append(splits, text[l:r])
+		l = r
+	}
+	return append(splits, text[l:])
+}
+
+// splitPoint find where to cut text between l (excluded) and max (included).
+func splitPoint(text string, l, max int) int {
+	fallback := 0
+	for p := max; p > l; p-- {
+		if !utf8.RuneStart(text[p]) || !isEscapeSafe(text[:p]) {
+			continue
+		}
+		if isWordBoundary(text[:p]) {
+			return p
+		}
+		if fallback == 0 {
+			fallback = p
+		}
+	}
+	if fallback != 0 {
+		return fallback
+	}
+
+	// maxLen is smaller than a single rune or escape sequence, still make progress
+	p := l
+	if text[p] == '\\' {
+		p++
+	}
+	_, size := utf8.DecodeRuneInString(text[p:])
+	return p + size
+}
+
+// isEscapeSafe report whether s doesn't end with an incomplete escape sequence,
+// ie. with an odd number of backslashes.
+func isEscapeSafe(s string) bool {
+	n := 0
+	for i := len(s) - 1; i >= 0 && s[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 0
+}
+
+// isWordBoundary report whether s ends with a whitespace, a punctuation
+// or an escaped new line.
+func isWordBoundary(s string) bool {
+	r, size := utf8.DecodeLastRuneInString(s)
+	if unicode.IsSpace(r) || unicode.IsPunct(r) {
+		return true
+	}
+	return r == 'n' && !isEscapeSafe(s[:len(s)-size])
+}
+
+// tokenize split a text into lower cased terms, in order of first appearance and without duplicates.
+// It only approximates the sonic tokenizer: words are runs of letters and digits.
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	seen := make(map[string]struct{}, len(fields))
+	terms := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f = strings.ToLower(f)
+		if _, ok := seen[f]; ok {
+			continue
+		}
+		seen[f] = struct{}{}
+		terms = append(terms, f)
+	}
+	return terms
+}
+
+// joinTerms join terms back into a text.
+func joinTerms(terms []string) string {
+	return strings.Join(terms, " ")
+}
+
+// diffTerms return the terms of newText missing from oldText (added)
+// and the terms of oldText missing from newText (removed).
+func diffTerms(oldText, newText string) (added, removed []string) {
+	oldTerms, newTerms := tokenize(oldText), tokenize(newText)
+
+	oldSet := make(map[string]struct{}, len(oldTerms))
+	for _, t := range oldTerms {
+		oldSet[t] = struct{}{}
+	}
+	newSet := make(map[string]struct{}, len(newTerms))
+	for _, t := range newTerms {
+		newSet[t] = struct{}{}
+		if _, ok := oldSet[t]; !ok {
+			added = append(added, t)
+		}
+	}
+	for _, t := range oldTerms {
+		if _, ok := newSet[t]; !ok {
+			removed = append(removed, t)
+		}
+	}
+	return added, removed
+}
@@ -0,0 +1,104 @@
+package sonic
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+)
+
+func TestDiffTerms(t *testing.T) {
+	added, removed := diffTerms("The quick brown fox", "the QUICK red fox, quick!")
+	if !reflect.DeepEqual(added, []string{"red"}) {
+		t.Errorf("added = %v, want [red]", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"brown"}) {
+		t.Errorf("removed = %v, want [brown]", removed)
+	}
+
+	added, removed = diffTerms("same text", "Same, text.")
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("expected no difference, got added = %v, removed = %v", added, removed)
+	}
+}
+
+// splitInput is a random text made of words, separators, escaped characters and multi-byte runes.
+type splitInput struct {
+	Text   string
+	MaxLen int
+}
+
+func (splitInput) Generate(rand *rand.Rand, size int) reflect.Value {
+	pieces := []string{"a", "bc", "word", "longerword", " ", "  ", ",", ".", "\n", "\"", "\\", "é", "日本", "🙂"}
+	var b strings.Builder
+	for n := rand.Intn(size * 4); n > 0; n-- {
+		b.WriteString(pieces[rand.Intn(len(pieces))])
+	}
+	return reflect.ValueOf(splitInput{
+		Text:   escapeText(b.String()),
+		MaxLen: 4 + rand.Intn(40),
+	})
+}
+
+func TestSplitText_Properties(t *testing.T) {
+	property := func(in splitInput) bool {
+		chunks := splitText(in.Text, in.MaxLen)
+		if strings.Join(chunks, "") != in.Text {
+			t.Logf("chunks %q don't rebuild %q", chunks, in.Text)
+			return false
+		}
+		for n, c := range chunks {
+			if len(c) > in.MaxLen {
+				t.Logf("chunk %q longer than %d", c, in.MaxLen)
+				return false
+			}
+			if !utf8.ValidString(c) {
+				t.Logf("chunk %q isn't valid utf-8", c)
+				return false
+			}
+			if !isEscapeSafe(c) {
+				t.Logf("chunk %q breaks an escape sequence", c)
+				return false
+			}
+			if n == len(chunks)-1 || isWordBoundary(c) {
+				continue
+			}
+			// a word may only be cut when the chunk has no boundary at all
+			for p := 1; p < len(c); p++ {
+				if utf8.RuneStart(c[p]) && isEscapeSafe(c[:p]) && isWordBoundary(c[:p]) {
+					t.Logf("chunk %q cuts a word but had a boundary at %d", c, p)
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		text   string
+		maxLen int
+		want   []string
+	}{
+		{"hello world", 0, []string{"hello world"}},
+		{"hello world", 20, []string{"hello world"}},
+		{"hello world", 8, []string{"hello ", "world"}},
+		{"hello, world", 7, []string{"hello, ", "world"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{`ab\"cd`, 3, []string{"ab", `\"`, "cd"}},
+		{`ab\ncd`, 4, []string{`ab\n`, "cd"}},
+		{"ééé", 3, []string{"é", "é", "é"}},
+	}
+	for _, tt := range tests {
+		got := splitText(tt.text, tt.maxLen)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitText(%q, %d) = %q, want %q", tt.text, tt.maxLen, got, tt.want)
+		}
+	}
+}